@@ -3,44 +3,69 @@ package markup
 import (
 	"encoding/json"
 	"reflect"
+	"sort"
 	"strconv"
 )
 
+var (
+	// booleanAttributes are the HTML attributes whose presence is
+	// significant, whatever their value. A boolean attribute set to "false"
+	// is considered absent.
+	booleanAttributes = map[string]bool{
+		"allowfullscreen": true,
+		"async":           true,
+		"autofocus":       true,
+		"autoplay":        true,
+		"checked":         true,
+		"controls":        true,
+		"default":         true,
+		"defer":           true,
+		"disabled":        true,
+		"formnovalidate":  true,
+		"hidden":          true,
+		"ismap":           true,
+		"loop":            true,
+		"multiple":        true,
+		"muted":           true,
+		"novalidate":      true,
+		"open":            true,
+		"readonly":        true,
+		"required":        true,
+		"reversed":        true,
+		"selected":        true,
+	}
+)
+
 // AttributeMap represents a map of attributes.
 type AttributeMap map[string]string
 
-func (m AttributeMap) diff(other AttributeMap) AttributeMap {
-	res := AttributeMap{}
+// diff returns the attributes to set and the names of the attributes to
+// remove in order to turn m into other. Removed names are sorted.
+func (m AttributeMap) diff(other AttributeMap) (set AttributeMap, removed []string) {
+	set = AttributeMap{}
 
-	for n, v := range m {
-		if len(other) == 0 {
-			res[n] = ""
-			continue
+	for n := range m {
+		if _, ok := other[n]; !ok {
+			removed = append(removed, n)
 		}
+	}
 
-		nv, ok := other[n]
-		if !ok {
-			res[n] = ""
+	for n, v := range other {
+		if ov, ok := m[n]; ok && ov == v {
 			continue
 		}
 
-		if v != nv {
-			res[n] = nv
-			continue
-		}
+		set[n] = v
 	}
 
-	for n, v := range other {
-		if len(m) != 0 {
-			ov, ok := m[n]
-			if ok && ov == v {
-				continue
-			}
-		}
+	sort.Strings(removed)
+	return
+}
 
-		res[n] = v
-	}
-	return res
+// isAbsentAttribute reports whether the attribute name with value must be
+// considered as absent from an HTML element.
+func isAbsentAttribute(name string, value string) bool {
+	return booleanAttributes[name] && value == "false"
 }
 
 func decodeAttributeMap(attributes AttributeMap, c Componer) {
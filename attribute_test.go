@@ -44,10 +44,14 @@ func TestAttributeMapDiff(t *testing.T) {
 		"same":   "miam",
 		"add":    "bar",
 	}
-	res := m1.diff(m2)
+	res, removed := m1.diff(m2)
 
-	if len(res["delete"]) != 0 {
-		t.Error(`res["delete"] should be empty:`, res["delete"])
+	if v, ok := res["delete"]; ok {
+		t.Error(`res["delete"] should not be in res:`, v)
+	}
+
+	if len(removed) != 1 || removed[0] != "delete" {
+		t.Error("removed should be [delete]:", removed)
 	}
 
 	if v, ok := res["same"]; ok {
@@ -74,7 +78,9 @@ func TestAttributeMapNoDiff(t *testing.T) {
 		"same2": "boo",
 	}
 
-	if l := len(m1.diff(m2)); l != 0 {
+	res, removed := m1.diff(m2)
+
+	if l := len(res) + len(removed); l != 0 {
 		t.Error("l should be 0:", l)
 	}
 }
@@ -84,14 +90,35 @@ func TestAttributeMapDiffOtherEmpty(t *testing.T) {
 		"delete": "dirty",
 	}
 	m2 := AttributeMap{}
-	res := m1.diff(m2)
+	res, removed := m1.diff(m2)
+
+	if l := len(res); l != 0 {
+		t.Error("l should be 0:", l)
+	}
 
-	if l := len(m1.diff(m2)); l != 1 {
-		t.Error("l should be 1:", l)
+	if len(removed) != 1 || removed[0] != "delete" {
+		t.Error("removed should be [delete]:", removed)
+	}
+}
+
+func TestAttributeMapDiffEmptyValue(t *testing.T) {
+	m1 := AttributeMap{}
+	m2 := AttributeMap{
+		"class": "",
+	}
+	res, removed := m1.diff(m2)
+
+	if v, ok := res["class"]; !ok || len(v) != 0 {
+		t.Error(`res["class"] should be set to an empty string`)
+	}
+
+	if l := len(removed); l != 0 {
+		t.Error("l should be 0:", l)
 	}
 
-	if len(res["delete"]) != 0 {
-		t.Error(`res[delete] should be empty:`, res["delete"])
+	// Setting an attribute to an empty string is not a removal.
+	if res, removed = m2.diff(AttributeMap{}); len(res) != 0 || len(removed) != 1 {
+		t.Error("class should be removed:", res, removed)
 	}
 }
 
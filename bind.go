@@ -0,0 +1,329 @@
+package markup
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// bindAttr is the attribute which binds the value of an element to a field of
+// its component, designated by a pipeline like in event attributes:
+//  <input type="email" bind="Form.Email" />
+//  <input type="checkbox" bind="Form.Subscribed" />
+//  <input type="radio" value="monthly" bind="Form.Plan" />
+// A bound element gets the value of the field in its value property, or in its
+// checked property for checkboxes, and writes the values typed by the user in
+// the field. A bound radio is checked when the field equals its value
+// attribute, and writes its value attribute in the field when it gets checked.
+// The component is then invalidated in order to be synchronized by the next
+// Flush: HandleEvent does not return syncs.
+// Values are written by the oninput attribute, or by the onchange attribute for
+// checkboxes, radios and selects: a bound element can't declare it.
+// Strings, bools, ints, uints, floats and time.Time fields are supported. Times
+// are formatted according to the type of the input: date, datetime-local,
+// time or RFC 3339 for the other types.
+const bindAttr = "bind"
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+// Validator is the interface that wraps Validate method.
+// Validate is called before a value typed by the user is written in the field
+// designated by a bind attribute. The value is not written when an error is
+// returned. The error is then returned by HandleEvent.
+type Validator interface {
+	Validate(field string, value interface{}) error
+}
+
+// expandBindings replaces the bind attributes of the elements of the tree n by
+// a property and an event call that writes in the bound field of c.
+func expandBindings(n *Node, c Componer) error {
+	if n.Type != HTMLNode {
+		return nil
+	}
+
+	if field, bound := n.Attributes[bindAttr]; bound {
+		if err := expandBinding(n, c, field); err != nil {
+			return err
+		}
+	}
+
+	for _, child := range n.Children {
+		if err := expandBindings(child, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func expandBinding(n *Node, c Componer, field string) error {
+	v, err := fieldValue(reflect.ValueOf(c), strings.Split(field, "."))
+	if err != nil {
+		return errors.Wrapf(err, "%T unable to bind %v", c, field)
+	}
+
+	value, err := formatBoundValue(v, n.Attributes["type"])
+	if err != nil {
+		return errors.Wrapf(err, "%T unable to bind %v", c, field)
+	}
+
+	if n.Properties == nil {
+		n.Properties = AttributeMap{}
+	}
+	if isRadio(n) {
+		value = strconv.FormatBool(value == n.Attributes["value"])
+	}
+	n.Properties[boundProperty(n)] = value
+
+	// The bound field is written by the event attribute: it can't call
+	// another handler.
+	event := boundEvent(n)
+	if _, declared := n.Attributes[event]; declared {
+		return errors.Errorf("%T unable to bind %v: %v is already declared", c, field, event)
+	}
+
+	if n.Events == nil {
+		n.Events = map[string]EventCall{}
+	}
+	n.Events[event] = EventCall{
+		Handler: field,
+		Bind:    true,
+	}
+	n.Attributes[event] = field
+	delete(n.Attributes, bindAttr)
+	return nil
+}
+
+func isRadio(n *Node) bool {
+	return n.Tag == "input" && n.Attributes["type"] == "radio"
+}
+
+// boundProperty returns the property which contains the value of the bound
+// element n.
+func boundProperty(n *Node) string {
+	if n.Tag == "input" && (n.Attributes["type"] == "checkbox" || n.Attributes["type"] == "radio") {
+		return "checked"
+	}
+	return "value"
+}
+
+// boundEvent returns the event attribute which reports the changes of the
+// value of the bound element n.
+func boundEvent(n *Node) string {
+	if n.Tag == "select" || boundProperty(n) == "checked" {
+		return "onchange"
+	}
+	return "oninput"
+}
+
+// fieldValue returns the value designated by pipeline from v. Unlike
+// setPipedValue, it does not modify v: nil pointers and missing map entries
+// give zero values.
+func fieldValue(v reflect.Value, pipeline []string) (reflect.Value, error) {
+	for _, name := range pipeline {
+		if len(name) == 0 {
+			return reflect.Value{}, errors.New("pipeline element can't be empty")
+		}
+
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v = reflect.Zero(v.Type().Elem())
+				continue
+			}
+			v = v.Elem()
+		}
+
+		switch k := v.Kind(); k {
+		case reflect.Struct:
+			if v = v.FieldByName(name); !v.IsValid() {
+				return reflect.Value{}, errors.Errorf("no field named %v", name)
+			}
+
+		case reflect.Map:
+			kv, err := pipedMapKey(v.Type(), name)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			if ev := v.MapIndex(kv); ev.IsValid() {
+				v = ev
+			} else {
+				v = reflect.Zero(v.Type().Elem())
+			}
+
+		case reflect.Slice, reflect.Array:
+			i, err := pipedIndex(v, name)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v = v.Index(i)
+
+		default:
+			return reflect.Value{}, errors.Errorf("%v is not a valid pipeline source", k)
+		}
+	}
+
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Zero(v.Type().Elem()), nil
+		}
+		v = v.Elem()
+	}
+	return v, nil
+}
+
+func formatBoundValue(v reflect.Value, inputType string) (string, error) {
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return "", nil
+		}
+		return t.Format(timeLayout(inputType)), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		return strconv.FormatInt(v.Int(), 10), nil
+
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		return strconv.FormatUint(v.Uint(), 10), nil
+
+	case reflect.Float64, reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+
+	default:
+		return "", errors.Errorf("%v cannot be bound", v.Type())
+	}
+}
+
+func parseBoundValue(t reflect.Type, value string, inputType string) (v reflect.Value, err error) {
+	v = reflect.New(t).Elem()
+
+	if t == timeType {
+		var tm time.Time
+		if len(value) != 0 {
+			if tm, err = time.Parse(timeLayout(inputType), value); err != nil {
+				return
+			}
+		}
+		v.Set(reflect.ValueOf(tm))
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(value)
+
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(value)
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		var n int64
+		n, err = strconv.ParseInt(value, 10, t.Bits())
+		v.SetInt(n)
+
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, t.Bits())
+		v.SetUint(n)
+
+	case reflect.Float64, reflect.Float32:
+		var n float64
+		n, err = strconv.ParseFloat(value, t.Bits())
+		v.SetFloat(n)
+
+	default:
+		err = errors.Errorf("%v cannot be bound", t)
+	}
+	return
+}
+
+func timeLayout(inputType string) string {
+	switch inputType {
+	case "date":
+		return "2006-01-02"
+
+	case "datetime-local":
+		return "2006-01-02T15:04"
+
+	case "time":
+		return "15:04"
+
+	default:
+		return time.RFC3339
+	}
+}
+
+// handleBinding writes the value of the ChangeEvent argJSON, sent by the bound
+// element n, in the field designated by call.
+func handleBinding(n *Node, call EventCall, argJSON string) error {
+	var e ChangeEvent
+	if err := json.Unmarshal([]byte(argJSON), &e); err != nil {
+		return errors.Wrapf(err, "unable to bind %v: unmarshal %v failed", call.Handler, argJSON)
+	}
+
+	c := n.Mount
+	value := e.Value
+
+	// A radio reports whether it is checked. Unchecking is the result of
+	// checking another radio of the group, which writes the field.
+	if isRadio(n) {
+		if e.Value != "true" {
+			return ReportProperty(n.ID, "checked", e.Value)
+		}
+		value = n.Attributes["value"]
+	}
+
+	var invalid error
+
+	err := setPipedValue(reflect.ValueOf(c), strings.Split(call.Handler, "."), func(fv reflect.Value) error {
+		for fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				fv.Set(reflect.New(fv.Type().Elem()))
+			}
+			fv = fv.Elem()
+		}
+
+		parsed, err := parseBoundValue(fv.Type(), value, n.Attributes["type"])
+		if err != nil {
+			return err
+		}
+
+		if validator, isValidator := c.(Validator); isValidator {
+			if invalid = validator.Validate(call.Handler, parsed.Interface()); invalid != nil {
+				return invalid
+			}
+		}
+
+		fv.Set(parsed)
+		return nil
+	})
+	if invalid != nil {
+		return invalid
+	}
+	if err != nil {
+		return errors.Wrapf(err, "unable to bind %v", call.Handler)
+	}
+
+	// The element already shows the value: it is not sent back by the
+	// synchronization.
+	if err = ReportProperty(n.ID, boundProperty(n), e.Value); err != nil {
+		return err
+	}
+
+	Invalidate(c)
+	return nil
+}
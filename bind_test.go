@@ -0,0 +1,354 @@
+package markup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+)
+
+type CompoBind struct {
+	Form struct {
+		Email      string
+		Age        int
+		Subscribed bool
+		Weight     float64
+		Birthday   time.Time
+	}
+}
+
+func (c *CompoBind) Render() string {
+	return `
+<form>
+    <input type="email" bind="Form.Email" />
+    <input type="number" bind="Form.Age" />
+    <input type="checkbox" bind="Form.Subscribed" />
+    <input type="number" bind="Form.Weight" />
+    <input type="date" bind="Form.Birthday" />
+    <p>{{.Form.Email}}</p>
+</form>
+    `
+}
+
+func (c *CompoBind) Validate(field string, value interface{}) error {
+	if field == "Form.Age" && value.(int) < 0 {
+		return errors.New("age can't be negative")
+	}
+	return nil
+}
+
+type CompoBindEventConflict struct {
+	Query string
+}
+
+func (c *CompoBindEventConflict) Render() string {
+	return `<input bind="Query" oninput="Search" />`
+}
+
+func (c *CompoBindEventConflict) Search(e ChangeEvent) {}
+
+type CompoBindBadField struct{}
+
+func (c *CompoBindBadField) Render() string {
+	return `<input bind="Email" />`
+}
+
+type CompoBindRadio struct {
+	Plan  string
+	Level int
+}
+
+func (c *CompoBindRadio) Render() string {
+	return `
+<form>
+    <input type="radio" name="plan" value="monthly" bind="Plan" />
+    <input type="radio" name="plan" value="yearly" bind="Plan" />
+    <input type="radio" name="level" value="1" bind="Level" />
+    <input type="radio" name="level" value="2" bind="Level" />
+</form>
+    `
+}
+
+func init() {
+	Register(&CompoBind{})
+	Register(&CompoBindBadField{})
+	Register(&CompoBindEventConflict{})
+	Register(&CompoBindRadio{})
+}
+
+func TestBind(t *testing.T) {
+	c := &CompoBind{}
+	c.Form.Email = "max@murlok.io"
+	c.Form.Age = 42
+	c.Form.Subscribed = true
+	c.Form.Weight = 72.5
+	c.Form.Birthday = time.Date(1986, 2, 14, 0, 0, 0, 0, time.UTC)
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	email := root.Children[0]
+	age := root.Children[1]
+	subscribed := root.Children[2]
+	weight := root.Children[3]
+	birthday := root.Children[4]
+
+	if _, bound := email.Attributes[bindAttr]; bound {
+		t.Error("bind attribute should have been expanded")
+	}
+
+	if value := email.Properties["value"]; value != "max@murlok.io" {
+		t.Error("email value should be max@murlok.io:", value)
+	}
+
+	if call := email.Events["oninput"]; !call.Bind || call.Handler != "Form.Email" {
+		t.Error("email should be bound by oninput:", call)
+	}
+
+	if value := age.Properties["value"]; value != "42" {
+		t.Error("age value should be 42:", value)
+	}
+
+	if checked := subscribed.Properties["checked"]; checked != "true" {
+		t.Error("subscribed should be checked:", checked)
+	}
+
+	if call := subscribed.Events["onchange"]; !call.Bind {
+		t.Error("subscribed should be bound by onchange:", call)
+	}
+
+	if value := weight.Properties["value"]; value != "72.5" {
+		t.Error("weight value should be 72.5:", value)
+	}
+
+	if value := birthday.Properties["value"]; value != "1986-02-14" {
+		t.Error("birthday value should be 1986-02-14:", value)
+	}
+
+	if err = HandleEvent(email.ID, "oninput", "Form.Email", `{"value": "maxence@murlok.io"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Form.Email != "maxence@murlok.io" {
+		t.Error("email should be maxence@murlok.io:", c.Form.Email)
+	}
+
+	if err = HandleEvent(age.ID, "oninput", "Form.Age", `{"value": "21"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Form.Age != 21 {
+		t.Error("age should be 21:", c.Form.Age)
+	}
+
+	if err = HandleEvent(subscribed.ID, "onchange", "Form.Subscribed", `{"value": "false"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Form.Subscribed {
+		t.Error("subscribed should be false")
+	}
+
+	if err = HandleEvent(weight.ID, "oninput", "Form.Weight", `{"value": "70.25"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Form.Weight != 70.25 {
+		t.Error("weight should be 70.25:", c.Form.Weight)
+	}
+
+	if err = HandleEvent(birthday.ID, "oninput", "Form.Birthday", `{"value": "1987-03-15"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if d := time.Date(1987, 3, 15, 0, 0, 0, 0, time.UTC); !c.Form.Birthday.Equal(d) {
+		t.Error("birthday should be", d, ":", c.Form.Birthday)
+	}
+
+	// The written values are already shown by the elements.
+	syncs, err := Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range syncs {
+		if len(s.Properties) != 0 {
+			t.Error("properties should not be sent:", s.Properties)
+		}
+	}
+}
+
+func TestBindSync(t *testing.T) {
+	c := &CompoBind{}
+	c.Form.Email = "max@murlok.io"
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	email := root.Children[0]
+	text := root.Children[5].Children[0]
+
+	// Bound writes are synchronized by the next Flush.
+	if err = HandleEvent(email.ID, "oninput", "Form.Email", `{"value": "maxence@murlok.io"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if text.Text != "max@murlok.io" {
+		t.Error("text should not be synchronized yet:", text.Text)
+	}
+
+	syncs, err := Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 1 {
+		t.Fatal("l should be 1:", l)
+	}
+
+	if s := syncs[0]; s.Scope != TextSync || s.Node != text || text.Text != "maxence@murlok.io" {
+		t.Errorf("bad sync: %+v", s)
+	}
+
+	// HandleEventSync returns them right away.
+	syncs, err = HandleEventSync(email.ID, "oninput", "Form.Email", `{"value": "max@murlok.io"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 1 {
+		t.Fatal("l should be 1:", l)
+	}
+
+	if text.Text != "max@murlok.io" {
+		t.Error("text should be max@murlok.io:", text.Text)
+	}
+}
+
+func TestBindError(t *testing.T) {
+	c := &CompoBind{}
+	c.Form.Age = 42
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	age := root.Children[1]
+
+	if err = HandleEvent(age.ID, "oninput", "Form.Age", `{"value": "forty-two"}`); err == nil {
+		t.Error("err should not be nil")
+	}
+
+	if err = HandleEvent(age.ID, "oninput", "Form.Age", `{"value": "-1"}`); err == nil {
+		t.Error("err should not be nil")
+	}
+
+	if c.Form.Age != 42 {
+		t.Error("age should be 42:", c.Form.Age)
+	}
+
+	if _, err = Mount(&CompoBindBadField{}, uuid.NewV1()); err == nil {
+		t.Error("err should not be nil")
+	}
+
+	// The bound event attribute is already declared.
+	if _, err = Mount(&CompoBindEventConflict{}, uuid.NewV1()); err == nil {
+		t.Error("err should not be nil")
+	}
+	t.Log(err)
+}
+
+func TestBindRadio(t *testing.T) {
+	c := &CompoBindRadio{
+		Plan:  "monthly",
+		Level: 2,
+	}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	monthly := root.Children[0]
+	yearly := root.Children[1]
+	level1 := root.Children[2]
+	level2 := root.Children[3]
+
+	for _, test := range []struct {
+		node    *Node
+		checked string
+	}{
+		{monthly, "true"},
+		{yearly, "false"},
+		{level1, "false"},
+		{level2, "true"},
+	} {
+		if checked := test.node.Properties["checked"]; checked != test.checked {
+			t.Errorf("%v checked should be %v: %v", test.node.Attributes["value"], test.checked, checked)
+		}
+
+		if call := test.node.Events["onchange"]; !call.Bind {
+			t.Error("radio should be bound by onchange:", call)
+		}
+	}
+
+	if err = HandleEvent(yearly.ID, "onchange", "Plan", `{"value": "true"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Plan != "yearly" {
+		t.Error("plan should be yearly:", c.Plan)
+	}
+
+	// Unchecking a radio does not write the field.
+	if err = HandleEvent(monthly.ID, "onchange", "Plan", `{"value": "false"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Plan != "yearly" {
+		t.Error("plan should be yearly:", c.Plan)
+	}
+
+	if err = HandleEvent(level1.ID, "onchange", "Level", `{"value": "true"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Level != 1 {
+		t.Error("level should be 1:", c.Level)
+	}
+
+	// Checked radios and reported unchecked ones already show their state.
+	// The radio unchecked by the browser without an event is unchecked again.
+	syncs, err := Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range syncs {
+		if len(s.Properties) == 0 {
+			continue
+		}
+
+		if s.Node != level2 || s.Properties["checked"] != "false" {
+			t.Errorf("only level 2 should be unchecked: %v %v", s.Node.Attributes["value"], s.Properties)
+		}
+	}
+
+	if checked := monthly.Properties["checked"]; checked != "false" {
+		t.Error("monthly should not be checked:", checked)
+	}
+
+	if checked := level1.Properties["checked"]; checked != "true" {
+		t.Error("level 1 should be checked:", checked)
+	}
+}
@@ -2,6 +2,7 @@ package markup
 
 import (
 	"reflect"
+	"strings"
 
 	"github.com/murlokswarm/log"
 	"github.com/pkg/errors"
@@ -10,7 +11,7 @@ import (
 
 var (
 	compoBuilders = map[string]func() Componer{}
-	components    = map[Componer]*component{}
+	components    = map[Componer][]*component{}
 	nodes         = map[uuid.UUID]*Node{}
 )
 
@@ -34,9 +35,35 @@ type Dismounter interface {
 	OnDismount()
 }
 
+// ErrorBoundary is the interface that wraps OnChildError method.
+// OnChildError is called when a component from the subtree of the boundary
+// fails to mount, render or synchronize. The failing component is replaced by
+// the returned fallback markup. An empty fallback replaces it by a hidden
+// span element, which keeps its place among its siblings.
+type ErrorBoundary interface {
+	OnChildError(err error) (fallback string)
+}
+
+// ErrorHandler is called with the errors caught by an error boundary.
+// Default implementation logs the error.
+var ErrorHandler = func(boundary Componer, err error) {
+	log.Errorf("%T caught an error: %v", boundary, err)
+}
+
+// component is the handle of a mounted component instance.
+// A component is identified by its handle rather than by its pointer since Go
+// may give the same address to every pointer of a zero-size struct. Each
+// instance has then its own root and nodes, whatever the size of the
+// component.
 type component struct {
-	Count int
-	Root  *Node
+	Component   Componer
+	Context     uuid.UUID
+	Parent      *component
+	Node        *Node
+	Root        *Node
+	Consumed    map[reflect.Type]consumption
+	Consumers   map[*component]bool
+	Fingerprint string
 }
 
 // Register registers a component. Allows the component to be dynamically
@@ -71,12 +98,47 @@ func Registered(c Componer) bool {
 }
 
 // Root returns the root node of c. Panic if c is not mounted.
+// When c is a zero-size component mounted several times, the root of the last
+// mounted instance is returned.
 func Root(c Componer) *Node {
-	compo, mounted := components[c]
-	if !mounted {
+	return instance(c).Root
+}
+
+// instance returns the last mounted instance of c. Panic if c is not mounted.
+func instance(c Componer) *component {
+	compos := components[c]
+	if len(compos) == 0 {
 		log.Panic(errors.Errorf("%T is not mounted", c))
 	}
-	return compo.Root
+	return compos[len(compos)-1]
+}
+
+func registerComponent(compo *component) {
+	c := compo.Component
+	components[c] = append(components[c], compo)
+	contexts[compo.Context]++
+}
+
+func unregisterComponent(compo *component) {
+	c := compo.Component
+	compos := components[c]
+
+	for i, mounted := range compos {
+		if mounted == compo {
+			compos = append(compos[:i], compos[i+1:]...)
+			break
+		}
+	}
+
+	if contexts[compo.Context]--; contexts[compo.Context] <= 0 {
+		delete(contexts, compo.Context)
+	}
+
+	if len(compos) == 0 {
+		delete(components, c)
+		return
+	}
+	components[c] = compos
 }
 
 // ID returns the id of c. Panic if c is not mounted.
@@ -111,134 +173,333 @@ func Markup(c Componer) string {
 }
 
 // Mount retains a component and its underlying nodes.
+// If an error occurs, everything mounted during the call is dismounted.
 func Mount(c Componer, ctx uuid.UUID) (root *Node, err error) {
+	txn := &transaction{}
+
+	compo, err := mount(c, ctx, nil, nil, txn)
+	if err != nil {
+		txn.rollback()
+		return
+	}
+
+	txn.commit()
+	root = compo.Root
+	return
+}
+
+func mount(c Componer, ctx uuid.UUID, parent *component, host *Node, txn *transaction) (compo *component, err error) {
 	if !Registered(c) {
 		err = errors.Errorf("%T is not registered", c)
 		return
 	}
 
-	if compo, mounted := components[c]; mounted {
-		// Go uses the same reference for different instances of a same empty struct.
-		// This prevents from mounting a same empty struct.
-		if t := reflect.TypeOf(c).Elem(); t.NumField() == 0 {
-			compo.Count++
-			root = compo.Root
-			return
-		}
-
+	// Go uses the same reference for different instances of a same empty
+	// struct. Those can be mounted several times, each mount creating a new
+	// instance.
+	if _, mounted := components[c]; mounted && reflect.TypeOf(c).Elem().Size() != 0 {
 		err = errors.Errorf("%T is already mounted", c)
 		return
 	}
 
-	r, err := render(c)
-	if err != nil {
-		err = errors.Errorf("unable to render %T: %v\n%v", c, err, c.Render())
-		return
+	savepoint := txn.savepoint()
+	compo = &component{
+		Component: c,
+		Context:   ctx,
+		Parent:    parent,
+		Node:      host,
 	}
 
-	if root, err = stringToNode(r); err != nil {
-		err = errors.Errorf("%T markup returned by Render() has a %v\n%v", c, err, r)
+	// The instance is registered before rendering to allow the component to
+	// consume the values provided by its ancestors.
+	registerComponent(compo)
+	txn.onRollback(func() {
+		releaseConsumer(compo)
+		unregisterComponent(compo)
+	})
+
+	compo.Fingerprint, _ = fingerprint(c)
+
+	if compo.Root, err = renderNode(c); err != nil {
 		return
 	}
 
-	if root.Type != HTMLNode {
-		err = errors.Errorf("%T markup returned by Render() has a syntax error: root node is not a HTMLNode\n%v", c, r)
+	if err = mountNode(compo.Root, compo, txn); err != nil {
 		return
 	}
 
-	if err = mountNode(root, c, ctx); err != nil {
+	if mounter, isMounter := c.(Mounter); isMounter {
+		if err = protect(func() error {
+			mounter.OnMount()
+			return nil
+		}); err != nil {
+			err = errors.Errorf("%T OnMount failed: %v", c, err)
+			return
+		}
+	}
+
+	// The component is now fully mounted: undoing it is dismounting it, which
+	// pairs OnMount with OnDismount.
+	txn.collapse(savepoint, func() {
+		dismountTxn := &transaction{}
+		dismount(compo, dismountTxn)
+		dismountTxn.commit()
+	})
+	return
+}
+
+// renderNode renders c and decodes the result into a tree of nodes.
+func renderNode(c Componer) (root *Node, err error) {
+	var r string
+
+	if err = protect(func() (err error) {
+		r, err = render(c)
+		return
+	}); err != nil {
+		// Render is not called again to show the markup: it may be the one
+		// which panicked.
+		err = errors.Errorf("unable to render %T: %v", c, err)
 		return
 	}
 
-	components[c] = &component{
-		Count: 1,
-		Root:  root,
+	if root, err = stringToNode(r); err != nil {
+		err = errors.Errorf("%T markup returned by Render() has a %v\n%v", c, err, r)
+		return
 	}
 
-	if mounter, isMounter := c.(Mounter); isMounter {
-		mounter.OnMount()
+	if root.Type != HTMLNode {
+		err = errors.Errorf("%T markup returned by Render() has a syntax error: root node is not a HTMLNode\n%v", c, r)
+		return
 	}
+
+	err = expandBindings(root, c)
 	return
 }
 
-func mountNode(n *Node, mount Componer, ctx uuid.UUID) error {
+func mountNode(n *Node, compo *component, txn *transaction) error {
 	switch n.Type {
 	case HTMLNode:
-		return mountHTMLNode(n, mount, ctx)
+		return mountHTMLNode(n, compo, txn)
 
 	case ComponentNode:
-		return mountComponentNode(n, mount, ctx)
+		return mountComponentNode(n, compo, txn)
+
+	case TextNode:
+		mountTextNode(n, compo)
 	}
 	return nil
 }
 
-func mountHTMLNode(n *Node, mount Componer, ctx uuid.UUID) error {
+// mountTextNode gives an id to n, which allows to designate it in syncs.
+// Text nodes are not registered since they cannot be the source of an event.
+func mountTextNode(n *Node, compo *component) {
 	n.ID = uuid.NewV1()
-	n.ContextID = ctx
-	n.Mount = mount
-	nodes[n.ID] = n
+	n.ContextID = compo.Context
+	n.Mount = compo.Component
+}
+
+func mountHTMLNode(n *Node, compo *component, txn *transaction) error {
+	id := uuid.NewV1()
+	n.ID = id
+	n.ContextID = compo.Context
+	n.Mount = compo.Component
+	setProperties(n, n.Properties)
+	nodes[id] = n
+	txn.onRollback(func() { delete(nodes, id) })
 
 	for _, c := range n.Children {
-		if err := mountNode(c, mount, ctx); err != nil {
+		if err := mountNode(c, compo, txn); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func mountComponentNode(n *Node, mount Componer, ctx uuid.UUID) error {
-	n.ContextID = ctx
-	n.Mount = mount
+func mountComponentNode(n *Node, compo *component, txn *transaction) error {
+	n.ContextID = compo.Context
+	n.Mount = compo.Component
+	savepoint := txn.savepoint()
+
+	var child *component
 
 	c, err := New(n.Tag)
+	if err == nil {
+		decodeAttributeMap(n.Attributes, c)
+		child, err = mount(c, compo.Context, compo, n, txn)
+	}
+
 	if err != nil {
-		return err
+		txn.rollbackTo(savepoint)
+		return recoverChildError(n, compo, err, txn)
 	}
 
-	decodeAttributeMap(n.Attributes, c)
+	n.Component = c
+	n.compo = child
+	return nil
+}
 
-	if _, err = Mount(c, ctx); err != nil {
+// recoverChildError replaces the failing component node n by the fallback of
+// the nearest error boundary. err is returned when there is no boundary.
+func recoverChildError(n *Node, parent *component, err error, txn *transaction) error {
+	boundary := findBoundary(parent)
+	if boundary == nil {
 		return err
 	}
+	return replaceByFallback(n, parent, boundary, err, txn)
+}
 
-	n.Component = c
+func findBoundary(compo *component) ErrorBoundary {
+	for ; compo != nil; compo = compo.Parent {
+		if boundary, isBoundary := compo.Component.(ErrorBoundary); isBoundary {
+			return boundary
+		}
+	}
 	return nil
 }
 
+func replaceByFallback(n *Node, parent *component, boundary ErrorBoundary, err error, txn *transaction) error {
+	fallback, ferr := fallbackNode(boundary, err)
+	if ferr != nil {
+		return errors.Errorf("%v\n%v", err, ferr)
+	}
+
+	ErrorHandler(boundary.(Componer), err)
+
+	txn.saveNode(n)
+	n.Type = fallback.Type
+	n.Tag = fallback.Tag
+	n.Text = fallback.Text
+	n.Attributes = fallback.Attributes
+	n.Properties = fallback.Properties
+	n.Events = fallback.Events
+	n.Component = nil
+	n.compo = nil
+	n.Children = fallback.Children
+
+	for _, c := range n.Children {
+		c.Parent = n
+	}
+	return mountNode(n, parent, txn)
+}
+
+func fallbackNode(boundary ErrorBoundary, err error) (n *Node, ferr error) {
+	var fallback string
+
+	if ferr = protect(func() error {
+		fallback = boundary.OnChildError(err)
+		return nil
+	}); ferr != nil {
+		ferr = errors.Errorf("%T OnChildError failed: %v", boundary, ferr)
+		return
+	}
+
+	// An empty fallback is replaced by a hidden element rather than by an
+	// empty text: drivers ignore whitespace, which would shift the indexes of
+	// the following siblings.
+	if len(strings.TrimSpace(fallback)) == 0 {
+		n = &Node{
+			Type:       HTMLNode,
+			Tag:        "span",
+			Attributes: AttributeMap{"hidden": "true"},
+		}
+		return
+	}
+
+	if n, ferr = stringToNode(fallback); ferr != nil {
+		ferr = errors.Errorf("%T fallback has a %v\n%v", boundary, ferr, fallback)
+		return
+	}
+
+	if n.Type != HTMLNode || hasComponentNode(n) {
+		ferr = errors.Errorf("%T fallback must only contain HTML elements\n%v", boundary, fallback)
+	}
+	return
+}
+
+func hasComponentNode(n *Node) bool {
+	if n.Type == ComponentNode {
+		return true
+	}
+
+	for _, c := range n.Children {
+		if hasComponentNode(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// protect calls f and converts a panic into an error.
+func protect(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("panic: %v", r)
+		}
+	}()
+	return f()
+}
+
 // Dismount dismounts a component.
+// When c is a zero-size component mounted several times, the last mounted
+// instance is dismounted.
 func Dismount(c Componer) {
-	compo, mounted := components[c]
-	if !mounted {
+	if _, mounted := components[c]; !mounted {
 		return
 	}
 
-	// Go uses the same reference for different instances of a same empty struct.
-	// This prevents from dismounting an empty struct that still remains in another context.
-	if compo.Count--; compo.Count == 0 {
-		dismountNode(compo.Root)
-		delete(components, c)
+	compo := instance(c)
+	txn := &transaction{}
+	dismount(compo, txn)
+	txn.commit()
+	notifyEmptyContext(compo.Context)
+}
 
-		if dismounter, isDismounter := c.(Dismounter); isDismounter {
-			dismounter.OnDismount()
+// dismount unregisters compo and its nodes. OnDismount is called when txn is
+// committed.
+func dismount(compo *component, txn *transaction) {
+	dismountNode(compo.Root, txn)
+
+	c := compo.Component
+	instances := append([]*component(nil), components[c]...)
+	consumed := compo.Consumed
+	invalidated := dirty[compo]
+
+	releaseConsumer(compo)
+	unregisterComponent(compo)
+	delete(dirty, compo)
+
+	txn.onRollback(func() {
+		components[c] = instances
+		contexts[compo.Context]++
+		restoreConsumer(compo, consumed)
+
+		if invalidated {
+			dirty[compo] = true
 		}
+	})
+
+	if dismounter, isDismounter := c.(Dismounter); isDismounter {
+		txn.onCommit(dismounter.OnDismount)
 	}
-	return
 }
 
-func dismountNode(n *Node) {
+func dismountNode(n *Node, txn *transaction) {
 	switch n.Type {
 	case HTMLNode:
-		dismountHTMLNode(n)
+		dismountHTMLNode(n, txn)
 
 	case ComponentNode:
-		Dismount(n.Component)
+		if n.compo != nil {
+			dismount(n.compo, txn)
+		}
 	}
 }
 
-func dismountHTMLNode(n *Node) {
+func dismountHTMLNode(n *Node, txn *transaction) {
 	for _, c := range n.Children {
-		dismountNode(c)
+		dismountNode(c, txn)
 	}
 
 	delete(nodes, n.ID)
+	txn.onRollback(func() { nodes[n.ID] = n })
 }
@@ -1,6 +1,7 @@
 package markup
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/satori/go.uuid"
@@ -67,6 +68,110 @@ func (c *CompoBadRoot) Render() string {
 	return `<CompoEmpty />`
 }
 
+type CompoPanic struct{}
+
+func (c *CompoPanic) OnMount() {
+	panic("CompoPanic is not ok")
+}
+
+func (c *CompoPanic) Render() string {
+	return `
+<div>
+    <CompoHooks />
+</div>
+    `
+}
+
+type CompoRenderPanic struct{}
+
+func (c *CompoRenderPanic) Render() string {
+	panic("CompoRenderPanic is not ok")
+}
+
+type CompoBoundary struct {
+	EmbedsNonRegistered bool
+	EmbedsPanic         bool
+	EmbedsRenderPanic   bool
+	EmbedsBadMarkup     bool
+	BreaksChild         bool
+	Fallback            string
+
+	err error
+}
+
+func (c *CompoBoundary) OnChildError(err error) string {
+	c.err = err
+	return c.Fallback
+}
+
+func (c *CompoBoundary) Render() string {
+	return `
+<div>
+    <CompoEmpty />
+
+    {{if .EmbedsNonRegistered}}
+        <CompoNotRegistered />
+    {{end}}
+
+    {{if .EmbedsPanic}}
+        <CompoPanic />
+    {{end}}
+
+    {{if .EmbedsRenderPanic}}
+        <CompoRenderPanic />
+    {{end}}
+
+    <CompoMount EmbedsBadMarkup="{{.EmbedsBadMarkup}}" />
+    <CompoSyncError BadTemplate="{{.BreaksChild}}" />
+</div>
+    `
+}
+
+var hooksMounted int
+
+type CompoHooks struct{}
+
+func (c *CompoHooks) OnMount() {
+	hooksMounted++
+}
+
+func (c *CompoHooks) OnDismount() {
+	hooksMounted--
+}
+
+func (c *CompoHooks) Render() string {
+	return `<p>CompoHooks</p>`
+}
+
+type CompoRollback struct {
+	EmbedsNonRegistered bool
+	EmbedsPanic         bool
+}
+
+func (c *CompoRollback) Render() string {
+	return `
+<div>
+    <CompoHooks />
+    <CompoMount EmbedsNonRegistered="{{.EmbedsNonRegistered}}" />
+
+    {{if .EmbedsPanic}}
+        <CompoPanic />
+    {{end}}
+</div>
+    `
+}
+
+type CompoZeroSizes struct{}
+
+func (c *CompoZeroSizes) Render() string {
+	return `
+<div>
+    <CompoEmpty />
+    <CompoEmpty />
+</div>
+    `
+}
+
 type compoNotExported struct{}
 
 func (c *compoNotExported) Render() string {
@@ -85,6 +190,12 @@ func init() {
 	Register(&CompoBadRenderTemplate{})
 	Register(&CompoBadMarkup{})
 	Register(&CompoBadRoot{})
+	Register(&CompoPanic{})
+	Register(&CompoRenderPanic{})
+	Register(&CompoBoundary{})
+	Register(&CompoHooks{})
+	Register(&CompoRollback{})
+	Register(&CompoZeroSizes{})
 }
 
 func TestRegisterNotExported(t *testing.T) {
@@ -236,3 +347,254 @@ func TestMountBadRoot(t *testing.T) {
 	c := &CompoBadRoot{}
 	Mount(c, ctx)
 }
+
+func TestMountPanic(t *testing.T) {
+	ctx := uuid.NewV1()
+	c := &CompoPanic{}
+
+	if _, err := Mount(c, ctx); err == nil {
+		t.Error("err should not be nil")
+	}
+}
+
+func TestMountErrorBoundary(t *testing.T) {
+	defer func(h func(Componer, error)) { ErrorHandler = h }(ErrorHandler)
+
+	tests := []struct {
+		scenario string
+		compo    *CompoBoundary
+	}{
+		{
+			scenario: "not registered",
+			compo:    &CompoBoundary{EmbedsNonRegistered: true},
+		},
+		{
+			scenario: "panic",
+			compo:    &CompoBoundary{EmbedsPanic: true},
+		},
+		{
+			scenario: "render panic",
+			compo:    &CompoBoundary{EmbedsRenderPanic: true},
+		},
+		{
+			scenario: "nested bad markup",
+			compo:    &CompoBoundary{EmbedsBadMarkup: true},
+		},
+	}
+
+	for _, test := range tests {
+		c := test.compo
+		c.Fallback = `<p class="fallback">Oops</p>`
+
+		var handled error
+		ErrorHandler = func(boundary Componer, err error) {
+			handled = err
+		}
+
+		if _, err := Mount(c, uuid.NewV1()); err != nil {
+			t.Fatalf("%v: %v", test.scenario, err)
+		}
+
+		if c.err == nil {
+			t.Errorf("%v: OnChildError should have been called", test.scenario)
+		}
+
+		if handled != c.err {
+			t.Errorf("%v: error should have been reported to ErrorHandler", test.scenario)
+		}
+
+		if m := Markup(c); !strings.Contains(m, "fallback") {
+			t.Errorf("%v: markup should contain the fallback: %v", test.scenario, m)
+		}
+
+		Dismount(c)
+	}
+}
+
+func TestMountErrorBoundaryEmptyFallback(t *testing.T) {
+	c := &CompoBoundary{EmbedsNonRegistered: true}
+
+	if _, err := Mount(c, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	m := Markup(c)
+	if strings.Contains(m, "CompoNotRegistered") {
+		t.Error("markup should not contain CompoNotRegistered:", m)
+	}
+
+	// The placeholder is an element: it counts as a child for drivers.
+	placeholder := Root(c).Children[1]
+
+	if placeholder.Type != HTMLNode || placeholder.Tag != "span" || placeholder.Attributes["hidden"] != "true" {
+		t.Errorf("placeholder should be a hidden span: %+v", placeholder)
+	}
+
+	if !strings.Contains(m, `<span data-murlok-id="`+placeholder.ID.String()+`" hidden="true"></span>`) {
+		t.Error("markup should contain the placeholder:", m)
+	}
+}
+
+func TestMountErrorBoundaryBadFallback(t *testing.T) {
+	c := &CompoBoundary{
+		EmbedsNonRegistered: true,
+		Fallback:            `<CompoEmpty />`,
+	}
+
+	if _, err := Mount(c, uuid.NewV1()); err == nil {
+		t.Error("err should not be nil")
+	}
+}
+
+func TestMountRollback(t *testing.T) {
+	tests := []struct {
+		scenario string
+		compo    Componer
+	}{
+		{
+			scenario: "nested not registered",
+			compo:    &CompoRollback{EmbedsNonRegistered: true},
+		},
+		{
+			scenario: "panic",
+			compo:    &CompoRollback{EmbedsPanic: true},
+		},
+		{
+			scenario: "root panic",
+			compo:    &CompoPanic{},
+		},
+	}
+
+	for _, test := range tests {
+		componentsLen := len(components)
+		nodesLen := len(nodes)
+
+		if _, err := Mount(test.compo, uuid.NewV1()); err == nil {
+			t.Fatalf("%v: err should not be nil", test.scenario)
+		}
+
+		if l := len(components); l != componentsLen {
+			t.Errorf("%v: components len should be %v: %v", test.scenario, componentsLen, l)
+		}
+
+		if l := len(nodes); l != nodesLen {
+			t.Errorf("%v: nodes len should be %v: %v", test.scenario, nodesLen, l)
+		}
+
+		if hooksMounted != 0 {
+			t.Errorf("%v: OnMount calls should be paired with OnDismount: %v", test.scenario, hooksMounted)
+		}
+	}
+}
+
+func TestMountErrorBoundaryRollback(t *testing.T) {
+	componentsLen := len(components)
+	nodesLen := len(nodes)
+
+	c := &CompoBoundary{EmbedsPanic: true}
+	if _, err := Mount(c, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+
+	for compo := range components {
+		if _, ok := compo.(*CompoPanic); ok {
+			t.Error("CompoPanic should have been rolled back")
+		}
+	}
+
+	if hooksMounted != 0 {
+		t.Error("CompoHooks embedded in CompoPanic should have been dismounted:", hooksMounted)
+	}
+
+	Dismount(c)
+
+	if l := len(components); l != componentsLen {
+		t.Errorf("components len should be %v: %v", componentsLen, l)
+	}
+
+	if l := len(nodes); l != nodesLen {
+		t.Errorf("nodes len should be %v: %v", nodesLen, l)
+	}
+}
+
+func TestMountZeroSize(t *testing.T) {
+	nodesLen := len(nodes)
+
+	c := &CompoZeroSizes{}
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := root.Children[0]
+	second := root.Children[1]
+
+	if first.compo == second.compo {
+		t.Fatal("each CompoEmpty should have its own instance")
+	}
+
+	if first.compo.Root == second.compo.Root {
+		t.Error("each CompoEmpty should have its own root")
+	}
+
+	if first.compo.Root.ID == second.compo.Root.ID {
+		t.Error("each CompoEmpty should have its own id")
+	}
+
+	Dismount(c)
+
+	if l := len(nodes); l != nodesLen {
+		t.Errorf("nodes len should be %v: %v", nodesLen, l)
+	}
+}
+
+func TestMountZeroSizeSeveralTimes(t *testing.T) {
+	c := &CompoEmpty{}
+	instancesLen := len(components[c])
+
+	root1, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root2, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if root1 == root2 || root1.ID == root2.ID {
+		t.Error("each mount should create a new instance")
+	}
+
+	if id := ID(c); id != root2.ID {
+		t.Errorf("ID should return the id of the last mounted instance %v: %v", root2.ID, id)
+	}
+
+	Dismount(c)
+	Dismount(c)
+
+	if l := len(components[c]); l != instancesLen {
+		t.Errorf("instances len should be %v: %v", instancesLen, l)
+	}
+}
+
+func TestMountTextNode(t *testing.T) {
+	c := &CompoEmpty{}
+	nodesLen := len(nodes)
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	text := root.Children[0]
+	if text.ID == uuid.Nil {
+		t.Error("text node should have an id")
+	}
+
+	if l := len(nodes) - nodesLen; l != 1 {
+		t.Error("only the root should have been registered:", l)
+	}
+}
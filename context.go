@@ -0,0 +1,73 @@
+package markup
+
+import "github.com/satori/go.uuid"
+
+var (
+	contexts = map[uuid.UUID]int{}
+)
+
+// EmptyContextHandler is called when the last component of a context is
+// dismounted. It allows a driver to release the resources associated with
+// the context, like a window.
+var EmptyContextHandler func(ctx uuid.UUID)
+
+// Components returns the components mounted in ctx, subcomponents included.
+// Components are returned in no particular order.
+func Components(ctx uuid.UUID) []Componer {
+	return contextComponents(ctx, func(compo *component) bool {
+		return true
+	})
+}
+
+// Roots returns the components mounted in ctx with Mount.
+// Components are returned in no particular order.
+func Roots(ctx uuid.UUID) []Componer {
+	return contextComponents(ctx, func(compo *component) bool {
+		return compo.Parent == nil
+	})
+}
+
+func contextComponents(ctx uuid.UUID, filter func(compo *component) bool) []Componer {
+	var compos []Componer
+
+	for c, instances := range components {
+		for _, compo := range instances {
+			if compo.Context == ctx && filter(compo) {
+				compos = append(compos, c)
+				break
+			}
+		}
+	}
+	return compos
+}
+
+// DismountContext dismounts all the components mounted in ctx.
+// Subcomponents are dismounted before their parents.
+func DismountContext(ctx uuid.UUID) {
+	var roots []*component
+
+	for _, instances := range components {
+		for _, compo := range instances {
+			if compo.Context == ctx && compo.Parent == nil {
+				roots = append(roots, compo)
+			}
+		}
+	}
+
+	txn := &transaction{}
+	for _, compo := range roots {
+		dismount(compo, txn)
+	}
+	txn.commit()
+
+	if len(roots) != 0 {
+		notifyEmptyContext(ctx)
+	}
+}
+
+func notifyEmptyContext(ctx uuid.UUID) {
+	if _, notEmpty := contexts[ctx]; notEmpty || EmptyContextHandler == nil {
+		return
+	}
+	EmptyContextHandler(ctx)
+}
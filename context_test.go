@@ -0,0 +1,148 @@
+package markup
+
+import (
+	"testing"
+
+	"github.com/satori/go.uuid"
+)
+
+var contextDismounts []string
+
+type CompoContextParent struct{}
+
+func (c *CompoContextParent) OnDismount() {
+	contextDismounts = append(contextDismounts, "parent")
+}
+
+func (c *CompoContextParent) Render() string {
+	return `
+<div>
+    <CompoContextChild />
+</div>
+    `
+}
+
+type CompoContextChild struct {
+	Name string
+}
+
+func (c *CompoContextChild) OnDismount() {
+	contextDismounts = append(contextDismounts, "child")
+}
+
+func (c *CompoContextChild) Render() string {
+	return `<p>Child</p>`
+}
+
+func init() {
+	Register(&CompoContextParent{})
+	Register(&CompoContextChild{})
+}
+
+func TestComponents(t *testing.T) {
+	ctx := uuid.NewV1()
+
+	c1 := &CompoContextParent{}
+	if _, err := Mount(c1, ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c1)
+
+	c2 := &CompoContextChild{}
+	if _, err := Mount(c2, ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c2)
+
+	c3 := &CompoContextChild{}
+	if _, err := Mount(c3, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c3)
+
+	if l := len(Components(ctx)); l != 3 {
+		t.Error("components len should be 3:", l)
+	}
+
+	roots := Roots(ctx)
+	if l := len(roots); l != 2 {
+		t.Fatal("roots len should be 2:", l)
+	}
+
+	for _, r := range roots {
+		if r != c1 && r != c2 {
+			t.Errorf("%T should not be a root", r)
+		}
+	}
+}
+
+func TestDismountContext(t *testing.T) {
+	defer func(h func(uuid.UUID)) { EmptyContextHandler = h }(EmptyContextHandler)
+
+	ctx := uuid.NewV1()
+	nodesLen := len(nodes)
+	contextDismounts = nil
+
+	var emptied uuid.UUID
+	EmptyContextHandler = func(ctx uuid.UUID) {
+		emptied = ctx
+	}
+
+	if _, err := Mount(&CompoContextParent{}, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	other := &CompoContextChild{}
+	if _, err := Mount(other, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(other)
+
+	DismountContext(ctx)
+
+	if l := len(Components(ctx)); l != 0 {
+		t.Error("components len should be 0:", l)
+	}
+
+	if l := len(nodes) - nodesLen; l != 1 {
+		t.Error("only the nodes of other should remain:", l)
+	}
+
+	if l := len(contextDismounts); l != 2 {
+		t.Fatal("contextDismounts len should be 2:", l)
+	}
+
+	if contextDismounts[0] != "child" || contextDismounts[1] != "parent" {
+		t.Error("child should be dismounted before its parent:", contextDismounts)
+	}
+
+	if emptied != ctx {
+		t.Errorf("EmptyContextHandler should have been called with %v: %v", ctx, emptied)
+	}
+}
+
+func TestDismountEmptiesContext(t *testing.T) {
+	defer func(h func(uuid.UUID)) { EmptyContextHandler = h }(EmptyContextHandler)
+
+	ctx := uuid.NewV1()
+	called := 0
+
+	EmptyContextHandler = func(uuid.UUID) {
+		called++
+	}
+
+	c1 := &CompoContextChild{}
+	c2 := &CompoContextChild{}
+	Mount(c1, ctx)
+	Mount(c2, ctx)
+
+	Dismount(c1)
+	if called != 0 {
+		t.Error("EmptyContextHandler should not have been called")
+	}
+
+	Dismount(c2)
+	if called != 1 {
+		t.Error("EmptyContextHandler should have been called once:", called)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
 	"strings"
 )
@@ -46,7 +47,10 @@ func (d *decoder) next() error {
 
 	switch t := token.(type) {
 	case xml.StartElement:
-		n := elementToNode(t)
+		n, err := elementToNode(t)
+		if err != nil {
+			return err
+		}
 
 		if d.root == nil {
 			d.root = n
@@ -75,12 +79,13 @@ func (d *decoder) next() error {
 			return errors.New("text nodes cannot be root")
 		}
 
+		n.Parent = d.current
 		d.current.Children = append(d.current.Children, n)
 	}
 	return d.next()
 }
 
-func elementToNode(e xml.StartElement) *Node {
+func elementToNode(e xml.StartElement) (*Node, error) {
 	tag := e.Name.Local
 	nodeType := HTMLNode
 
@@ -89,8 +94,53 @@ func elementToNode(e xml.StartElement) *Node {
 	}
 
 	attributes := AttributeMap{}
+	var properties AttributeMap
+	var events map[string]EventCall
 
 	for _, attr := range e.Attr {
+		if nodeType == HTMLNode && attr.Name.Space == propNamespace {
+			if properties == nil {
+				properties = AttributeMap{}
+			}
+			properties[attr.Name.Local] = attr.Value
+			continue
+		}
+
+		// Components receive "false" as a value while elements drop the
+		// boolean attributes which are turned off.
+		if nodeType == HTMLNode && isAbsentAttribute(attr.Name.Local, attr.Value) {
+			continue
+		}
+
+		// Elements only keep the handler and the modifiers of event calls,
+		// which are wired by drivers. The call itself is kept in the node.
+		if nodeType == HTMLNode && isMarkupEvent(attr.Name.Local) {
+			call, err := parseEventCall(attr.Value)
+			if err != nil {
+				return nil, fmt.Errorf("syntax error in %v attribute: %v", attr.Name.Local, err)
+			}
+
+			if events == nil {
+				events = map[string]EventCall{}
+			}
+			events[attr.Name.Local] = call
+			attributes[attr.Name.Local] = call.Handler + call.Modifiers.String()
+			continue
+		}
+
+		// Components keep their event attributes as they are: the calls are
+		// the handlers of the events they emit.
+		if nodeType == ComponentNode && isMarkupEvent(attr.Name.Local) {
+			if events == nil {
+				events = map[string]EventCall{}
+			}
+			call, err := parseEventCall(attr.Value)
+			if err != nil {
+				return nil, fmt.Errorf("syntax error in %v attribute: %v", attr.Name.Local, err)
+			}
+			events[attr.Name.Local] = call
+		}
+
 		attributes[attr.Name.Local] = attr.Value
 	}
 
@@ -98,7 +148,9 @@ func elementToNode(e xml.StartElement) *Node {
 		Type:       nodeType,
 		Tag:        tag,
 		Attributes: attributes,
-	}
+		Properties: properties,
+		Events:     events,
+	}, nil
 }
 
 func charDataToNode(d xml.CharData) *Node {
@@ -80,3 +80,29 @@ func TestDecoderDecodeInvalid(t *testing.T) {
 		t.Error("should error")
 	}
 }
+
+func TestDecoderDecodeBooleanAttributes(t *testing.T) {
+	n, err := stringToNode(`
+<div>
+    <input type="checkbox" checked="false" disabled="true" />
+    <Bar hidden="false" />
+</div>
+    `)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := n.Children[0]
+
+	if _, checked := input.Attributes["checked"]; checked {
+		t.Error("checked should be absent")
+	}
+
+	if _, disabled := input.Attributes["disabled"]; !disabled {
+		t.Error("disabled should be present")
+	}
+
+	if hidden := n.Children[1].Attributes["hidden"]; hidden != "false" {
+		t.Error("component hidden attribute should be false:", hidden)
+	}
+}
@@ -0,0 +1,81 @@
+package markup
+
+// Diff returns the syncs which transform the tree old into the tree new. It
+// follows the rules used to synchronize mounted components, including keyed
+// children and removed attributes, but it does not modify nor mount any of the
+// trees. It allows to compare two renderings, like to update a page rendered
+// on the server side.
+//
+// Nodes which are kept are designated by their node in old: ids and parent
+// ids are the ones of old nodes. Nodes which are inserted or which replace an
+// old node, as well as the nodes of TextSyncs, are nodes of new.
+// Syncs also designate their nodes by Path, which allows to apply them to a
+// tree which is not mounted, like a page which is rendered by a server and
+// updated in a browser.
+// Component nodes are compared like elements: they are not rendered.
+// When the roots of old and new are not of the same kind, a single FullSync of
+// new is returned.
+func Diff(old *Node, new *Node) []Sync {
+	if !sameKind(old, new) || (old.Type == TextNode && old.Text != new.Text) {
+		return []Sync{{
+			Scope: FullSync,
+			Path:  []int{},
+			Node:  new,
+		}}
+	}
+	return diffNodes(old, new, []int{})
+}
+
+// diffNodes returns the syncs which transform old into new, old being at path.
+func diffNodes(old *Node, new *Node, path []int) (syncs []Sync) {
+	matches, replaces := match(old.Children, new.Children)
+	children := reconciledChildren(old.Children, new.Children, matches)
+	syncs = childSyncs(old.ID, old.Children, children, matches, replaces)
+
+	for i := range syncs {
+		syncs[i].Path = path
+	}
+
+	for i, j := range matches {
+		if j == -1 {
+			continue
+		}
+
+		c := old.Children[j]
+		if c.Type != TextNode {
+			childPath := make([]int, len(path), len(path)+1)
+			copy(childPath, path)
+			syncs = append(syncs, diffNodes(c, new.Children[i], append(childPath, i))...)
+			continue
+		}
+
+		if c.Text != new.Children[i].Text {
+			syncs = append(syncs, Sync{
+				Scope:    TextSync,
+				ParentID: old.ID,
+				Path:     path,
+				Index:    i,
+				Node:     new.Children[i],
+			})
+		}
+	}
+
+	set, removed := old.Attributes.diff(new.Attributes)
+	props, _ := old.Properties.diff(new.Properties)
+
+	if len(set) != 0 || len(removed) != 0 || len(props) != 0 {
+		s := Sync{
+			Scope:             AttrSync,
+			Path:              path,
+			Node:              old,
+			Attributes:        set,
+			RemovedAttributes: removed,
+		}
+
+		if len(props) != 0 {
+			s.Properties = props
+		}
+		syncs = append([]Sync{s}, syncs...)
+	}
+	return
+}
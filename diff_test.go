@@ -0,0 +1,271 @@
+package markup
+
+import "testing"
+
+func diffTestNodes(t *testing.T, old string, new string) (*Node, *Node) {
+	o, err := stringToNode(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := stringToNode(new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o, n
+}
+
+// copyTree returns a deep copy of the tree n, which keeps the IDs of its
+// nodes.
+func copyTree(n *Node) *Node {
+	c := *n
+	c.Children = make([]*Node, len(n.Children))
+
+	for i, child := range n.Children {
+		c.Children[i] = copyTree(child)
+	}
+	return &c
+}
+
+// assertEqualTrees reports the differences between the trees expected and n:
+// node types, tags, IDs, texts, attributes, properties and children.
+func assertEqualTrees(t *testing.T, expected *Node, n *Node) {
+	if n.Type != expected.Type || n.Tag != expected.Tag || n.ID != expected.ID || n.Text != expected.Text {
+		t.Errorf("node should be %v %v %v %q: %v %v %v %q",
+			expected.Type, expected.Tag, expected.ID, expected.Text,
+			n.Type, n.Tag, n.ID, n.Text)
+	}
+
+	if !equalAttributes(n.Attributes, expected.Attributes) {
+		t.Errorf("%v attributes should be %v: %v", n.Tag, expected.Attributes, n.Attributes)
+	}
+
+	if !equalAttributes(n.Properties, expected.Properties) {
+		t.Errorf("%v properties should be %v: %v", n.Tag, expected.Properties, n.Properties)
+	}
+
+	if len(n.Children) != len(expected.Children) {
+		t.Errorf("%v children len should be %v: %v", n.Tag, len(expected.Children), len(n.Children))
+		return
+	}
+
+	for i, c := range n.Children {
+		assertEqualTrees(t, expected.Children[i], c)
+	}
+}
+
+// equalAttributes reports whether a and b have the same attributes. A nil map
+// equals an empty one.
+func equalAttributes(a AttributeMap, b AttributeMap) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for name, value := range a {
+		if v, set := b[name]; !set || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// applySyncsByPath applies syncs to the tree root by designating their nodes
+// by path only, like a driver of a tree which is not mounted.
+func applySyncsByPath(t *testing.T, root *Node, syncs []Sync) *Node {
+	for _, s := range syncs {
+		if s.Path == nil {
+			t.Fatalf("sync path should be set: %+v", s)
+		}
+
+		if s.Scope == FullSync && len(s.Path) == 0 {
+			root = s.Node
+			continue
+		}
+
+		n := root
+		for _, i := range s.Path {
+			n = n.Children[i]
+		}
+
+		switch s.Scope {
+		case AttrSync:
+			attrs := AttributeMap{}
+			for name, value := range n.Attributes {
+				attrs[name] = value
+			}
+
+			for name, value := range s.Attributes {
+				attrs[name] = value
+			}
+
+			for _, name := range s.RemovedAttributes {
+				delete(attrs, name)
+			}
+			n.Attributes = attrs
+
+		case InsertChild:
+			n.Children = append(n.Children[:s.Index], append([]*Node{s.Node}, n.Children[s.Index:]...)...)
+
+		case RemoveChild:
+			n.Children = append(n.Children[:s.Index], n.Children[s.Index+1:]...)
+
+		case MoveChild:
+			c := n.Children[s.From]
+			n.Children = append(n.Children[:s.From], n.Children[s.From+1:]...)
+			n.Children = append(n.Children[:s.Index], append([]*Node{c}, n.Children[s.Index:]...)...)
+
+		case ReplaceChild:
+			n.Children[s.Index] = s.Node
+
+		case TextSync:
+			c := *n.Children[s.Index]
+			c.Text = s.Node.Text
+			n.Children[s.Index] = &c
+
+		default:
+			t.Fatalf("unexpected sync: %+v", s)
+		}
+	}
+	return root
+}
+
+func TestDiff(t *testing.T) {
+	old, new := diffTestNodes(t,
+		`<div class="a" hidden="true"><p>Hello</p></div>`,
+		`<div class=""><p>World</p></div>`,
+	)
+
+	oldTree := copyTree(old)
+	nodesLen := len(nodes)
+	syncs := Diff(old, new)
+
+	if l := len(syncs); l != 2 {
+		t.Fatal("l should be 2:", l)
+	}
+
+	s := syncs[0]
+
+	if s.Scope != AttrSync || s.Node != old {
+		t.Error("s should be an AttrSync of old")
+	}
+
+	if v, set := s.Attributes["class"]; !set || len(v) != 0 {
+		t.Error("class should be set to an empty string:", s.Attributes)
+	}
+
+	if len(s.RemovedAttributes) != 1 || s.RemovedAttributes[0] != "hidden" {
+		t.Error("hidden should be removed:", s.RemovedAttributes)
+	}
+
+	if s = syncs[1]; s.Scope != TextSync || s.Node.Text != "World" || s.Index != 0 {
+		t.Errorf("s should be a TextSync of World at 0: %+v", s)
+	}
+
+	// old should not be modified.
+	assertEqualTrees(t, oldTree, old)
+
+	if l := len(nodes); l != nodesLen {
+		t.Errorf("nodes len should be %v: %v", nodesLen, l)
+	}
+}
+
+func TestDiffKeyed(t *testing.T) {
+	old, new := diffTestNodes(t,
+		`<ul><li key="1">1</li><li key="2">2</li><li key="3">3</li></ul>`,
+		`<ul><li key="3">3</li><li key="1">1</li><li key="4">4</li></ul>`,
+	)
+
+	children := make([]*Node, len(old.Children))
+	copy(children, old.Children)
+	children = applyChildSyncs(t, children, Diff(old, new))
+
+	if l := len(children); l != 3 {
+		t.Fatal("l should be 3:", l)
+	}
+
+	for i, c := range children {
+		if key := new.Children[i].Attributes["key"]; c.Attributes["key"] != key {
+			t.Errorf("child %v key should be %v: %v", i, key, c.Attributes["key"])
+		}
+	}
+
+	if children[0] != old.Children[2] || children[1] != old.Children[0] {
+		t.Error("kept children should be the old nodes")
+	}
+
+	if children[2] != new.Children[2] {
+		t.Error("inserted child should be the new node")
+	}
+
+	if l := len(old.Children); l != 3 {
+		t.Error("old children should not be modified:", l)
+	}
+}
+
+func TestDiffRoot(t *testing.T) {
+	old, new := diffTestNodes(t, `<div></div>`, `<span></span>`)
+	syncs := Diff(old, new)
+
+	if l := len(syncs); l != 1 {
+		t.Fatal("l should be 1:", l)
+	}
+
+	if s := syncs[0]; s.Scope != FullSync || s.Node != new || s.Path == nil || len(s.Path) != 0 {
+		t.Errorf("s should be a FullSync of new at the root: %+v", s)
+	}
+}
+
+func TestDiffEqual(t *testing.T) {
+	markup := `
+<div class="a">
+    <input type="text" prop:value="Maxence" />
+    <SubCompoSync Name="Max" />
+    <p>Hello</p>
+</div>
+    `
+	old, new := diffTestNodes(t, markup, markup)
+
+	if l := len(Diff(old, new)); l != 0 {
+		t.Error("l should be 0:", l)
+	}
+}
+
+func TestDiffByPath(t *testing.T) {
+	old, new := diffTestNodes(t,
+		`<div class="a">
+            <ul><li key="1">1</li><li key="2">2</li><li key="3">3</li></ul>
+            <p title="x">Hello</p>
+            <span>Gone</span>
+        </div>`,
+		`<div class="b">
+            <ul><li key="3">3</li><li key="1" class="first">one</li><li key="4">4</li></ul>
+            <p>World</p>
+            <h1>New</h1>
+        </div>`,
+	)
+
+	syncs := Diff(old, new)
+
+	// Drivers of trees which are not mounted receive syncs without ids.
+	data, err := MarshalSyncs(syncs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := UnmarshalSyncs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqualTrees(t, new, applySyncsByPath(t, copyTree(old), decoded))
+
+	if data, err = MarshalSyncsBinary(syncs); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded, err = UnmarshalSyncsBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqualTrees(t, new, applySyncsByPath(t, copyTree(old), decoded))
+}
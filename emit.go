@@ -0,0 +1,57 @@
+package markup
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Emit emits the event name from the component c to the component which
+// mounted it. The parent binds a handler to the event with an event
+// attribute on the tag of c:
+//  <Dialog onconfirm="Save" />
+//  <Tabs onselect="Select('main')" />
+// The handler is called like the handlers of the events sent by drivers, with
+// payload in place of the JSON argument: a method receives it decoded into its
+// payload parameter and a field is mapped with it when it is a ChangeEvent.
+// It goes through the middlewares registered with Use and its error is
+// returned by Emit. The event is sent by the root node of c: it is the node
+// designated by the EventContext of a method.
+// Emit does nothing when c is a root component or when the event is not bound
+// by the parent.
+func Emit(c Componer, name string, payload interface{}) error {
+	compos, mounted := components[c]
+	if !mounted {
+		return errors.Errorf("%T is not mounted", c)
+	}
+
+	argJSON, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrapf(err, "%T unable to emit %v: marshal payload failed", c, name)
+	}
+
+	for _, compo := range compos {
+		host := compo.Node
+		if host == nil {
+			continue
+		}
+
+		call, bound := host.Events["on"+name]
+		if !bound {
+			continue
+		}
+
+		if err = handler()(Event{
+			Node:      compo.Root,
+			Component: host.Mount,
+			Attribute: "on" + name,
+			Handler:   call.Handler,
+			Call:      call,
+			Declared:  true,
+			Args:      string(argJSON),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
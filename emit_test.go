@@ -0,0 +1,115 @@
+package markup
+
+import (
+	"testing"
+
+	"github.com/satori/go.uuid"
+)
+
+type ConfirmPayload struct {
+	Value string
+}
+
+type CompoEmit struct {
+	Handler string
+
+	saved    string
+	savedBy  uuid.UUID
+	selected string
+}
+
+func (c *CompoEmit) Render() string {
+	return `
+<div>
+    <CompoEmitDialog onconfirm="{{.Handler}}" />
+    <CompoEmitDialog onconfirm="Select('main')" />
+    <CompoEmitDialog />
+</div>
+    `
+}
+
+func (c *CompoEmit) Save(ctx EventContext, p ConfirmPayload) {
+	c.saved = p.Value
+	c.savedBy = ctx.NodeID
+}
+
+func (c *CompoEmit) Select(tab string, p ConfirmPayload) {
+	c.selected = tab + ":" + p.Value
+}
+
+type CompoEmitDialog struct {
+	Title string
+}
+
+func (c *CompoEmitDialog) Render() string {
+	return `<dialog>{{.Title}}</dialog>`
+}
+
+func init() {
+	Register(&CompoEmit{})
+	Register(&CompoEmitDialog{})
+}
+
+func TestEmit(t *testing.T) {
+	c := &CompoEmit{Handler: "Save"}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	first := root.Children[0].Component
+	second := root.Children[1].Component
+	third := root.Children[2].Component
+
+	if err = Emit(first, "confirm", ConfirmPayload{Value: "ok"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.saved != "ok" {
+		t.Error("c.saved should be ok:", c.saved)
+	}
+
+	// The event is sent by the root of the emitting component.
+	if id := Root(first).ID; c.savedBy != id || id == uuid.Nil {
+		t.Errorf("c.savedBy should be %v: %v", id, c.savedBy)
+	}
+
+	// Static arguments.
+	if err = Emit(second, "confirm", ConfirmPayload{Value: "ok"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.selected != "main:ok" {
+		t.Error("c.selected should be main:ok:", c.selected)
+	}
+
+	// Unbound event.
+	if err = Emit(third, "confirm", ConfirmPayload{}); err != nil {
+		t.Error(err)
+	}
+
+	// Root component.
+	if err = Emit(c, "confirm", ConfirmPayload{}); err != nil {
+		t.Error(err)
+	}
+
+	// Handler changed by a synchronization.
+	c.Handler = "Unknown"
+
+	if _, err = Synchronize(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Emit(first, "confirm", ConfirmPayload{}); err == nil {
+		t.Error("err should not be nil")
+	}
+	t.Log(err)
+}
+
+func TestEmitNotMounted(t *testing.T) {
+	if err := Emit(&CompoEmitDialog{}, "confirm", nil); err == nil {
+		t.Error("err should not be nil")
+	}
+}
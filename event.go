@@ -0,0 +1,203 @@
+package markup
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// EventModifiers represents the modifiers of an event call:
+//  prevent          calls preventDefault on the native event.
+//  stop             calls stopPropagation on the native event.
+//  debounce:<d>     calls the handler once no event occurred for d.
+//  throttle:<d>     calls the handler at most once every d.
+//  key:<key>        calls the handler only for the given key, like Enter.
+// Durations follow the time.ParseDuration format. A number without unit is a
+// number of milliseconds. Several key modifiers can be combined.
+type EventModifiers struct {
+	PreventDefault  bool
+	StopPropagation bool
+	Debounce        time.Duration
+	Throttle        time.Duration
+	Keys            []string
+}
+
+func (m EventModifiers) isZero() bool {
+	return !m.PreventDefault &&
+		!m.StopPropagation &&
+		m.Debounce == 0 &&
+		m.Throttle == 0 &&
+		len(m.Keys) == 0
+}
+
+func (m EventModifiers) acceptKey(key string) bool {
+	for _, k := range m.Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the modifiers as they are written in an event attribute,
+// each one preceded by a pipe.
+func (m EventModifiers) String() string {
+	var b bytes.Buffer
+
+	if m.PreventDefault {
+		b.WriteString("|prevent")
+	}
+
+	if m.StopPropagation {
+		b.WriteString("|stop")
+	}
+
+	if m.Debounce > 0 {
+		b.WriteString("|debounce:")
+		b.WriteString(m.Debounce.String())
+	}
+
+	if m.Throttle > 0 {
+		b.WriteString("|throttle:")
+		b.WriteString(m.Throttle.String())
+	}
+
+	for _, k := range m.Keys {
+		b.WriteString("|key:")
+		b.WriteString(k)
+	}
+	return b.String()
+}
+
+// MarshalJSON satisfies the json.Marshaler interface. Modifiers are sent to
+// drivers as the last argument of CallEvent:
+//  {
+//    "prevent": true,
+//    "stop": true,
+//    "debounce": 300,  // Milliseconds.
+//    "throttle": 1000, // Milliseconds.
+//    "keys": ["Enter"]
+//  }
+// Modifiers which are not set are omitted.
+func (m EventModifiers) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		PreventDefault  bool     `json:"prevent,omitempty"`
+		StopPropagation bool     `json:"stop,omitempty"`
+		Debounce        int64    `json:"debounce,omitempty"`
+		Throttle        int64    `json:"throttle,omitempty"`
+		Keys            []string `json:"keys,omitempty"`
+	}{
+		PreventDefault:  m.PreventDefault,
+		StopPropagation: m.StopPropagation,
+		Debounce:        int64(m.Debounce / time.Millisecond),
+		Throttle:        int64(m.Throttle / time.Millisecond),
+		Keys:            m.Keys,
+	})
+}
+
+func parseModifierDuration(v string) (time.Duration, error) {
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// MouseEvent represents the payload of a mouse event.
+//  {
+//    "clientX": 42, "clientY": 21,
+//    "pageX": 42, "pageY": 21,
+//    "screenX": 42, "screenY": 21,
+//    "button": 0,
+//    "detail": 1,
+//    "altKey": false, "ctrlKey": false, "metaKey": false, "shiftKey": false
+//  }
+// Button is the pressed button: 0 for the main button, 1 for the auxiliary
+// button and 2 for the secondary button. Detail is the click count.
+type MouseEvent struct {
+	ClientX  float64 `json:"clientX"`
+	ClientY  float64 `json:"clientY"`
+	PageX    float64 `json:"pageX"`
+	PageY    float64 `json:"pageY"`
+	ScreenX  float64 `json:"screenX"`
+	ScreenY  float64 `json:"screenY"`
+	Button   int     `json:"button"`
+	Detail   int     `json:"detail"`
+	AltKey   bool    `json:"altKey"`
+	CtrlKey  bool    `json:"ctrlKey"`
+	MetaKey  bool    `json:"metaKey"`
+	ShiftKey bool    `json:"shiftKey"`
+}
+
+// KeyboardEvent represents the payload of a keyboard event.
+//  {
+//    "key": "a",
+//    "code": "KeyA",
+//    "location": 0,
+//    "repeat": false,
+//    "altKey": false, "ctrlKey": false, "metaKey": false, "shiftKey": false
+//  }
+// Key is the value of the key, like "a" or "Enter". Code is the physical key,
+// regardless of the keyboard layout.
+type KeyboardEvent struct {
+	Key      string `json:"key"`
+	Code     string `json:"code"`
+	Location int    `json:"location"`
+	Repeat   bool   `json:"repeat"`
+	AltKey   bool   `json:"altKey"`
+	CtrlKey  bool   `json:"ctrlKey"`
+	MetaKey  bool   `json:"metaKey"`
+	ShiftKey bool   `json:"shiftKey"`
+}
+
+// WheelEvent represents the payload of a wheel event.
+//  {
+//    <MouseEvent fields>,
+//    "deltaX": 0, "deltaY": 120, "deltaZ": 0,
+//    "deltaMode": 0
+//  }
+// DeltaMode is the unit of the deltas: 0 for pixels, 1 for lines and 2 for
+// pages.
+type WheelEvent struct {
+	MouseEvent
+	DeltaX    float64 `json:"deltaX"`
+	DeltaY    float64 `json:"deltaY"`
+	DeltaZ    float64 `json:"deltaZ"`
+	DeltaMode int     `json:"deltaMode"`
+}
+
+// ChangeEvent represents the payload of a change or an input event.
+//  {
+//    "value": "Hello"
+//  }
+// Value is the value of the element, like the text of an input. Checkboxes
+// and radio buttons send "true" or "false".
+type ChangeEvent struct {
+	Value string `json:"value"`
+}
+
+// FormEvent represents the payload of a submit or a reset event.
+//  {
+//    "values": {"name": "Maxence", "email": "max@murlok.io"}
+//  }
+// Values contains the values of the named elements of the form.
+type FormEvent struct {
+	Values map[string]string `json:"values"`
+}
+
+// DragEvent represents the payload of a drag and drop event.
+//  {
+//    <MouseEvent fields>,
+//    "files": ["/Users/Maxence/Desktop/murlok.png"]
+//  }
+// Files contains the dragged files: their path when the driver has access to
+// the file system, otherwise their name.
+type DragEvent struct {
+	MouseEvent
+	Files []string `json:"files"`
+}
+
+// FocusEvent represents the payload of a focus event.
+//  {}
+type FocusEvent struct {
+}
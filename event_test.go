@@ -0,0 +1,75 @@
+package markup
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/satori/go.uuid"
+)
+
+func TestEventPayloads(t *testing.T) {
+	var mouse MouseEvent
+	if err := json.Unmarshal([]byte(`{
+		"clientX": 42, "clientY": 21,
+		"pageX": 42, "pageY": 21,
+		"screenX": 42, "screenY": 21,
+		"button": 2,
+		"detail": 1,
+		"altKey": false, "ctrlKey": true, "metaKey": false, "shiftKey": false
+	}`), &mouse); err != nil {
+		t.Fatal(err)
+	}
+
+	if mouse.ClientX != 42 || mouse.Button != 2 || !mouse.CtrlKey {
+		t.Errorf("bad mouse event: %+v", mouse)
+	}
+
+	var key KeyboardEvent
+	if err := json.Unmarshal([]byte(`{"key": "Enter", "code": "Enter", "shiftKey": true}`), &key); err != nil {
+		t.Fatal(err)
+	}
+
+	if key.Key != "Enter" || !key.ShiftKey {
+		t.Errorf("bad keyboard event: %+v", key)
+	}
+
+	var wheel WheelEvent
+	if err := json.Unmarshal([]byte(`{"clientX": 42, "deltaY": 120}`), &wheel); err != nil {
+		t.Fatal(err)
+	}
+
+	if wheel.ClientX != 42 || wheel.DeltaY != 120 {
+		t.Errorf("bad wheel event: %+v", wheel)
+	}
+
+	var form FormEvent
+	if err := json.Unmarshal([]byte(`{"values": {"name": "Maxence"}}`), &form); err != nil {
+		t.Fatal(err)
+	}
+
+	if form.Values["name"] != "Maxence" {
+		t.Errorf("bad form event: %+v", form)
+	}
+
+	var drag DragEvent
+	if err := json.Unmarshal([]byte(`{"pageX": 21, "files": ["murlok.png"]}`), &drag); err != nil {
+		t.Fatal(err)
+	}
+
+	if drag.PageX != 21 || len(drag.Files) != 1 {
+		t.Errorf("bad drag event: %+v", drag)
+	}
+}
+
+func TestHandleEventChange(t *testing.T) {
+	c := &HandlerCompo{}
+	Mount(c, uuid.NewV1())
+	defer Dismount(c)
+
+	// Piped fields are set with the value of a ChangeEvent.
+	HandleEvent(ID(c), "", "String", `{"value": "Maxence"}`)
+
+	if c.String != "Maxence" {
+		t.Error("c.String should be Maxence:", c.String)
+	}
+}
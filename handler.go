@@ -1,133 +1,308 @@
 package markup
 
 import (
+	"bytes"
 	"encoding/json"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/murlokswarm/log"
 	"github.com/pkg/errors"
 	"github.com/satori/go.uuid"
 )
 
+var (
+	eventContextType = reflect.TypeOf(EventContext{})
+	errorType        = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// EventContext describes the context in which an event handler is called.
+// A handler method receives it when it is its first parameter.
+type EventContext struct {
+	NodeID    uuid.UUID
+	ContextID uuid.UUID
+	Handler   string
+	Source    *Node
+}
+
+// EventCall represents the call of an event handler declared in an event
+// attribute:
+//  <button onclick="Select({{.ID}}, 'primary')">Select</button>
+//  <input onkeyup="Search|debounce:300ms|key:Enter" />
+// Args are the static arguments of the call, which are decoded into the
+// parameters of the handler method when the event is handled. String
+// arguments are quoted with single or double quotes. Values written by
+// templates should be quoted with the arg template func, which escapes their
+// quotes:
+//  <button onclick="Rename({{arg .Name}})">Rename</button>
+// A malformed call is a render error.
+// Modifiers follow the call, separated by pipes.
+// Bind reports whether the call comes from a bind attribute, in which case
+// Handler is the pipeline of the bound field.
+type EventCall struct {
+	Handler   string
+	Args      []string
+	Modifiers EventModifiers
+	Bind      bool
+}
+
 // HandleEvent is a helper function to handle events.
-// If name designates a component method, the method will be called with argJSON
-// unmarshaled into the first arg.
+// event is the event attribute of the node which calls name, like onclick, as
+// sent by the CallEvent function of drivers. It is empty for the handlers
+// which are not called by an event attribute.
+// If name designates a component method, the method will be called with the
+// static arguments of its call, followed by argJSON unmarshaled into its
+// payload parameter. A method can have one of the following forms, with or
+// without an error return:
+//  func(args...)
+//  func(args..., payload T)
+//  func(ctx EventContext, args...)
+//  func(ctx EventContext, args..., payload T)
+// Static arguments and modifiers are the ones of the call declared by the
+// event attribute. Events which are filtered out by the key or the throttle
+// modifiers are ignored.
+// The error returned by the method is returned by HandleEvent, as well as an
+// error when a static argument does not convert to its parameter.
 // If name designates a component field, argJSON "Value" field will be directly
-// mapped in the component field.
-func HandleEvent(nodeID uuid.UUID, name string, argJSON string) {
+// mapped in the component field. Field paths can go through slice and array
+// indexes and map keys, like "Items.3.Title". Fields bound with a bind
+// attribute are converted to the type of the field and validated. Once
+// written, their component is invalidated, even when AutoSync is disabled:
+// HandleEvent does not synchronize it, its syncs are returned by the next
+// Flush, or by HandleEventSync.
+// Handlers which are not declared by the event attribute nor allowed by the
+// component are rejected with a *HandlerNotAllowedError.
+// The dispatch is wrapped by the middlewares registered with Use.
+// Panic if name is empty.
+func HandleEvent(nodeID uuid.UUID, event string, name string, argJSON string) error {
 	if len(name) == 0 {
 		log.Panic("no handler")
 	}
 
 	n, mounted := nodes[nodeID]
 	if !mounted {
-		log.Error(errors.Errorf("node with ID = %v does not belong to a mounted component.", nodeID))
-		return
+		return errors.Errorf("node with ID = %v does not belong to a mounted component", nodeID)
+	}
+
+	call, declared := eventCall(n, event, name)
+
+	return handler()(Event{
+		Node:      n,
+		Component: n.Mount,
+		Attribute: event,
+		Handler:   name,
+		Call:      call,
+		Declared:  declared,
+		Args:      argJSON,
+	})
+}
+
+// dispatchEvent is the Handler which calls or maps the handler of e. It is
+// wrapped by the middlewares.
+func dispatchEvent(e Event) error {
+	n, name, argJSON := e.Node, e.Handler, e.Args
+	call := e.Call
+
+	if !e.Declared && !allowHandler(e.Component, name) {
+		return &HandlerNotAllowedError{
+			NodeID:    n.ID,
+			Component: e.Component,
+			Handler:   name,
+		}
+	}
+
+	if !acceptEvent(n, e.Attribute, call, argJSON) {
+		return nil
 	}
 
-	c := n.Mount
+	if call.Bind {
+		return handleBinding(n, call, argJSON)
+	}
+
+	c := e.Component
 	v := reflect.ValueOf(c)
 
 	if m := v.MethodByName(name); m.IsValid() {
-		if err := callComponentMethod(m, argJSON); err != nil {
-			err = errors.Wrapf(err, "unable to call %v", name)
-			log.Error(err)
+		ctx := EventContext{
+			NodeID:    n.ID,
+			ContextID: n.ContextID,
+			Handler:   name,
+			Source:    n,
 		}
-		return
+		if err := callComponentMethod(m, ctx, call.Args, argJSON); err != nil {
+			return err
+		}
+
+		autoSync(c)
+		return nil
 	}
 
-	pv, err := getPipedValue(v, strings.Split(name, "."))
-	if err != nil {
-		log.Errorf("unable to map %v: %v", name, err)
-		return
+	if len(call.Args) != 0 {
+		return errors.Errorf("unable to map %v: static arguments are not supported: %v", name, call.Args)
 	}
 
-	if err = mapPipedValue(pv, argJSON); err != nil {
-		log.Errorf("unable to map %v: %v", name, err)
-		return
+	if err := setPipedValue(v, strings.Split(name, "."), func(fv reflect.Value) error {
+		return mapPipedValue(fv, argJSON)
+	}); err != nil {
+		return errors.Wrapf(err, "unable to map %v", name)
 	}
+
+	autoSync(c)
+	return nil
 }
 
-func callComponentMethod(m reflect.Value, argJSON string) error {
+// HandleEventSync handles an event like HandleEvent and returns the syncs of
+// the components invalidated so far, as returned by Flush. With AutoSync
+// enabled, they include the synchronization of the component which handled
+// the event.
+// Drivers which handle several events within a frame should rather call
+// HandleEvent for each one and then Flush once.
+func HandleEventSync(nodeID uuid.UUID, event string, name string, argJSON string) ([]Sync, error) {
+	if err := HandleEvent(nodeID, event, name, argJSON); err != nil {
+		return nil, err
+	}
+	return Flush()
+}
+
+// callComponentMethod calls the handler method m with the static arguments
+// staticArgs. The error returned by m is returned as is. m is not called when
+// a static argument cannot be decoded into its parameter.
+func callComponentMethod(m reflect.Value, ctx EventContext, staticArgs []string, argJSON string) error {
 	t := m.Type()
 	numIn := t.NumIn()
+	var args []reflect.Value
 
-	if numIn > 1 {
-		return errors.Errorf("func has more than 1 arg: %v", numIn)
+	if numIn > 0 && t.In(0) == eventContextType {
+		args = append(args, reflect.ValueOf(ctx))
 	}
 
-	if numIn == 0 {
-		m.Call([]reflect.Value{})
-		return nil
+	if numIn-len(args) < len(staticArgs) {
+		return errors.Errorf("unable to call %v: func has less args than the %v static args", ctx.Handler, len(staticArgs))
 	}
 
-	argt := t.In(0)
-	argv := reflect.New(argt)
-	argi := argv.Interface()
-	arg := argv.Elem()
+	for _, arg := range staticArgs {
+		argv, err := decodeStaticArg(t.In(len(args)), arg)
+		if err != nil {
+			return errors.Wrapf(err, "unable to call %v: bad static arg %q", ctx.Handler, arg)
+		}
+		args = append(args, argv)
+	}
 
-	if err := json.Unmarshal([]byte(argJSON), argi); err != nil {
-		return errors.Wrapf(err, "unmarshal %v failed", argJSON)
+	switch payloads := numIn - len(args); payloads {
+	case 0:
+
+	case 1:
+		argv := reflect.New(t.In(numIn - 1))
+
+		if err := json.Unmarshal([]byte(argJSON), argv.Interface()); err != nil {
+			return errors.Wrapf(err, "unable to call %v: unmarshal %v failed", ctx.Handler, argJSON)
+		}
+		args = append(args, argv.Elem())
+
+	default:
+		return errors.Errorf("unable to call %v: func has more than 1 payload arg: %v", ctx.Handler, payloads)
 	}
 
-	m.Call([]reflect.Value{arg})
+	if numOut := t.NumOut(); numOut > 1 || (numOut == 1 && t.Out(0) != errorType) {
+		return errors.Errorf("unable to call %v: func can only return an error", ctx.Handler)
+	}
+
+	if out := m.Call(args); len(out) == 1 && !out[0].IsNil() {
+		return out[0].Interface().(error)
+	}
 	return nil
 }
 
-func getPipedValue(v reflect.Value, pipeline []string) (rv reflect.Value, err error) {
+// decodeStaticArg returns the static argument arg decoded into a value of type
+// t. Unlike the attributes of components, static arguments are strictly
+// decoded: a value which does not convert to t is an error rather than a zero
+// value. Pointers are allocated, empty interfaces get arg as a string and
+// structs, slices, arrays and maps are decoded from JSON.
+func decodeStaticArg(t reflect.Type, arg string) (reflect.Value, error) {
+	switch k := t.Kind(); {
+	case k == reflect.Ptr:
+		v, err := decodeStaticArg(t.Elem(), arg)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		p := reflect.New(t.Elem())
+		p.Elem().Set(v)
+		return p, nil
+
+	case k == reflect.Interface && t.NumMethod() == 0:
+		return reflect.ValueOf(arg), nil
+
+	case t != timeType && (k == reflect.Struct || k == reflect.Slice || k == reflect.Map || k == reflect.Array):
+		v := reflect.New(t)
+		if err := json.Unmarshal([]byte(arg), v.Interface()); err != nil {
+			return reflect.Value{}, err
+		}
+		return v.Elem(), nil
+
+	default:
+		return parseBoundValue(t, arg, "")
+	}
+}
+
+// setPipedValue calls set with the value designated by pipeline from v.
+// Pipeline elements are struct fields, slice or array indexes and map keys,
+// which are parsed into the key type of the map. Nil pointers and maps are
+// allocated along the way.
+// Map entries are not addressable: set is called with a copy of the entry,
+// which is stored back in the map when set succeeds.
+func setPipedValue(v reflect.Value, pipeline []string, set func(reflect.Value) error) error {
 	if len(pipeline) == 0 {
-		rv = v
-		return
+		return set(v)
 	}
 	if len(pipeline[0]) == 0 {
-		err = errors.New("pipeline element can't be empty")
-		return
+		return errors.New("pipeline element can't be empty")
 	}
 
 	switch k := v.Kind(); k {
 	case reflect.Ptr:
-		return getPipedPtrValue(v, pipeline)
+		return setPipedPtrValue(v, pipeline, set)
 
 	case reflect.Struct:
-		return getPipedStructFieldValue(v, pipeline)
+		return setPipedStructFieldValue(v, pipeline, set)
 
 	case reflect.Map:
-		return getPipedMapValue(v, pipeline)
+		return setPipedMapValue(v, pipeline, set)
+
+	case reflect.Slice, reflect.Array:
+		return setPipedIndexValue(v, pipeline, set)
 
 	default:
-		err = errors.Errorf("%v is not a valid pipeline source", k)
+		return errors.Errorf("%v is not a valid pipeline source", k)
 	}
-	return
 }
 
-func getPipedPtrValue(v reflect.Value, pipeline []string) (rv reflect.Value, err error) {
+func setPipedPtrValue(v reflect.Value, pipeline []string, set func(reflect.Value) error) error {
 	if v.IsNil() {
 		t := v.Type()
 		nv := reflect.New(t.Elem())
 		v.Set(nv)
 	}
-	return getPipedValue(v.Elem(), pipeline)
+	return setPipedValue(v.Elem(), pipeline, set)
 }
 
-func getPipedStructFieldValue(v reflect.Value, pipeline []string) (rv reflect.Value, err error) {
+func setPipedStructFieldValue(v reflect.Value, pipeline []string, set func(reflect.Value) error) error {
 	fv := v.FieldByName(pipeline[0])
 	if !fv.IsValid() {
-		err = errors.Errorf("no field named %v", pipeline[0])
-		return
+		return errors.Errorf("no field named %v", pipeline[0])
 	}
-	return getPipedValue(fv, pipeline[1:])
+	return setPipedValue(fv, pipeline[1:], set)
 }
 
-func getPipedMapValue(v reflect.Value, pipeline []string) (rv reflect.Value, err error) {
+func setPipedMapValue(v reflect.Value, pipeline []string, set func(reflect.Value) error) error {
 	t := v.Type()
-	kt := t.Key()
-	if k := kt.Kind(); k != reflect.String {
-		err = errors.Errorf("%v key type must be a %v: %v",
-			pipeline[0],
-			reflect.String,
-			k)
-		return
+
+	kv, err := pipedMapKey(t, pipeline[0])
+	if err != nil {
+		return err
 	}
 
 	if v.IsNil() {
@@ -135,10 +310,48 @@ func getPipedMapValue(v reflect.Value, pipeline []string) (rv reflect.Value, err
 		v.Set(nv)
 	}
 
-	kv := reflect.ValueOf(pipeline[0])
-	vv := reflect.Zero(t.Elem())
-	v.SetMapIndex(kv, vv)
-	return getPipedValue(vv, pipeline[1:])
+	ev := reflect.New(t.Elem()).Elem()
+	if current := v.MapIndex(kv); current.IsValid() {
+		ev.Set(current)
+	}
+
+	if err = setPipedValue(ev, pipeline[1:], set); err != nil {
+		return err
+	}
+	v.SetMapIndex(kv, ev)
+	return nil
+}
+
+func setPipedIndexValue(v reflect.Value, pipeline []string, set func(reflect.Value) error) error {
+	i, err := pipedIndex(v, pipeline[0])
+	if err != nil {
+		return err
+	}
+	return setPipedValue(v.Index(i), pipeline[1:], set)
+}
+
+// pipedMapKey returns the key of the map type t which is written name in a
+// pipeline.
+func pipedMapKey(t reflect.Type, name string) (reflect.Value, error) {
+	kv, err := parseBoundValue(t.Key(), name, "")
+	if err != nil {
+		return reflect.Value{}, errors.Wrapf(err, "%v is not a valid %v key", name, t)
+	}
+	return kv, nil
+}
+
+// pipedIndex returns the index of the slice or array v which is written name
+// in a pipeline.
+func pipedIndex(v reflect.Value, name string) (int, error) {
+	i, err := strconv.Atoi(name)
+	if err != nil {
+		return 0, errors.Errorf("%v is not a valid %v index", name, v.Type())
+	}
+
+	if l := v.Len(); i < 0 || i >= l {
+		return 0, errors.Errorf("%v index out of range: %v", v.Type(), i)
+	}
+	return i, nil
 }
 
 func mapPipedValue(v reflect.Value, argJSON string) error {
@@ -162,3 +375,258 @@ func mapPipedValue(v reflect.Value, argJSON string) error {
 	v.Set(pv.Elem())
 	return nil
 }
+
+// parseEventCall parses the value of an event attribute: a call followed by
+// its modifiers, separated by pipes. A call without parentheses is a call
+// without arguments.
+// An error is returned when the call is malformed, like when a quote or a
+// parenthesis is not closed, or when a modifier is unknown or invalid. Values which are written into the arguments by
+// templates should be quoted with the arg template func.
+func parseEventCall(v string) (EventCall, error) {
+	parts := splitUnquoted(v, '|')
+
+	call, err := parseCall(parts[0])
+	if err != nil {
+		return call, errors.Wrapf(err, "invalid event call %q", v)
+	}
+
+	if call.Modifiers, err = parseEventModifiers(parts[1:]); err != nil {
+		return call, errors.Wrapf(err, "invalid event call %q", v)
+	}
+	return call, nil
+}
+
+// splitUnquoted splits s around the separators which are not quoted.
+func splitUnquoted(s string, sep rune) (parts []string) {
+	var quote rune
+	var escaped bool
+	start := 0
+
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+
+		case r == '\\' && quote != 0:
+			escaped = true
+
+		case r == quote:
+			quote = 0
+
+		case quote != 0:
+
+		case r == '\'' || r == '"':
+			quote = r
+
+		case r == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseEventModifiers parses the modifiers which follow a call. An error is
+// returned when a modifier is unknown or has an invalid value.
+func parseEventModifiers(modifiers []string) (m EventModifiers, err error) {
+	for _, modifier := range modifiers {
+		modifier = strings.TrimSpace(modifier)
+		name, value := modifier, ""
+
+		if i := strings.IndexRune(modifier, ':'); i != -1 {
+			name = modifier[:i]
+			value = strings.TrimSpace(modifier[i+1:])
+		}
+
+		switch name {
+		case "prevent":
+			m.PreventDefault = true
+
+		case "stop":
+			m.StopPropagation = true
+
+		case "debounce":
+			m.Debounce, err = parseModifierDuration(value)
+
+		case "throttle":
+			m.Throttle, err = parseModifierDuration(value)
+
+		case "key":
+			if len(value) == 0 {
+				err = errors.New("missing key")
+			}
+			m.Keys = append(m.Keys, value)
+
+		default:
+			err = errors.New("unknown modifier")
+		}
+
+		if err != nil {
+			err = errors.Wrapf(err, "invalid event modifier %q", modifier)
+			return
+		}
+	}
+	return
+}
+
+// parseCall parses a call like Select(42, 'primary'). An error is returned
+// when the call is malformed or when its handler is not a method name or a
+// field path.
+func parseCall(v string) (call EventCall, err error) {
+	v = strings.TrimSpace(v)
+
+	if open := strings.IndexRune(v, '('); open == -1 {
+		call.Handler = v
+	} else {
+		if !strings.HasSuffix(v, ")") {
+			err = errors.New("missing closing parenthesis")
+			return
+		}
+
+		call.Handler = strings.TrimSpace(v[:open])
+
+		if call.Args, err = parseCallArgs(v[open+1 : len(v)-1]); err != nil {
+			return
+		}
+	}
+
+	if !isHandlerName(call.Handler) {
+		err = errors.Errorf("invalid handler name %q", call.Handler)
+	}
+	return
+}
+
+// parseCallArgs parses the comma separated arguments of a call. String
+// arguments are quoted with single or double quotes, in which quotes and
+// backslashes are escaped with a backslash.
+func parseCallArgs(args string) ([]string, error) {
+	if len(strings.TrimSpace(args)) == 0 {
+		return nil, nil
+	}
+
+	var parsed []string
+	var arg bytes.Buffer
+	var quote rune
+	var escaped, quoted, closed bool
+
+	appendArg := func() error {
+		v := arg.String()
+		if !quoted {
+			if v = strings.TrimSpace(v); len(v) == 0 {
+				return errors.New("empty argument")
+			}
+		}
+
+		parsed = append(parsed, v)
+		arg.Reset()
+		quoted = false
+		closed = false
+		return nil
+	}
+
+	for _, r := range args {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+
+		case quote != 0 && r == '\\':
+			escaped = true
+
+		case quote != 0 && r == quote:
+			quote = 0
+			closed = true
+
+		case quote != 0:
+			arg.WriteRune(r)
+
+		case r == ',':
+			if err := appendArg(); err != nil {
+				return nil, err
+			}
+
+		case closed:
+			if !unicode.IsSpace(r) {
+				return nil, errors.Errorf("unexpected %q after a quoted argument", r)
+			}
+
+		case r == '\'' || r == '"':
+			if len(strings.TrimSpace(arg.String())) != 0 {
+				return nil, errors.Errorf("unexpected %q in an argument", r)
+			}
+
+			arg.Reset()
+			quote = r
+			quoted = true
+
+		case r == '(' || r == ')':
+			return nil, errors.Errorf("unexpected %q in an argument", r)
+
+		default:
+			arg.WriteRune(r)
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unterminated quoted argument")
+	}
+
+	if err := appendArg(); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// isHandlerName reports whether v is the name of a method or a field path.
+func isHandlerName(v string) bool {
+	if len(v) == 0 {
+		return false
+	}
+
+	for _, r := range v {
+		if r != '_' && r != '.' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// eventCall returns the call to handler declared by the event attribute event
+// of n. declared reports whether the attribute calls handler.
+func eventCall(n *Node, event string, handler string) (call EventCall, declared bool) {
+	if call, declared = n.Events[event]; declared && call.Handler == handler {
+		return
+	}
+	return EventCall{Handler: handler}, false
+}
+
+// acceptEvent enforces the key and the throttle modifiers of call, in case the
+// driver does not. call is declared by the event attribute event of n: each
+// event attribute is throttled separately.
+func acceptEvent(n *Node, event string, call EventCall, argJSON string) bool {
+	modifiers := call.Modifiers
+
+	if len(modifiers.Keys) != 0 {
+		var e KeyboardEvent
+		json.Unmarshal([]byte(argJSON), &e)
+
+		if !modifiers.acceptKey(e.Key) {
+			return false
+		}
+	}
+
+	if modifiers.Throttle <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if last, called := n.throttled[event]; called && now.Sub(last) < modifiers.Throttle {
+		return false
+	}
+
+	if n.throttled == nil {
+		n.throttled = map[string]time.Time{}
+	}
+	n.throttled[event] = now
+	return true
+}
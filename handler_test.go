@@ -1,9 +1,11 @@
 package markup
 
 import (
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/satori/go.uuid"
 )
@@ -14,14 +16,32 @@ type FuncArg struct {
 }
 
 type HandlerCompo struct {
-	String     string
-	Struct     FuncArg
-	StructPtr  *FuncArg
-	Map        map[string]int
-	InvalidMap map[int]string
+	String    string   `markup:"handler"`
+	Struct    FuncArg  `markup:"handler"`
+	StructPtr *FuncArg `markup:"handler"`
+	Map       map[string]int
+	IntMap    map[int]string
+	StructMap map[string]FuncArg `markup:"handler"`
+	Slice     []FuncArg
+	Array     [2]FuncArg
 
 	isCalledWithNoArg     bool
 	isCalledWithSingleArg bool
+	ctx                   EventContext
+}
+
+var errHandler = errors.New("handler failed")
+
+func (c *HandlerCompo) EventHandlers() []string {
+	return []string{
+		"HandlerWithoutArg",
+		"HandlerWitSingleArg",
+		"HandlerWitMultipleArg",
+		"HandlerWithContext",
+		"HandlerWithError",
+		"HandlerWithBadReturn",
+		"HandlerWithPanic",
+	}
 }
 
 func (c *HandlerCompo) HandlerWithoutArg() {
@@ -36,12 +56,101 @@ func (c *HandlerCompo) HandlerWitSingleArg(arg FuncArg) {
 func (c *HandlerCompo) HandlerWitMultipleArg(arg FuncArg, number int) {
 }
 
+func (c *HandlerCompo) HandlerWithContext(ctx EventContext, arg FuncArg) error {
+	c.ctx = ctx
+	c.Struct = arg
+	return nil
+}
+
+func (c *HandlerCompo) HandlerWithError() error {
+	return errHandler
+}
+
+func (c *HandlerCompo) HandlerWithStaticArgs(number int, ptr *int, arg FuncArg) {
+	c.Struct = FuncArg{Number: number + *ptr, String: arg.String}
+}
+
+func (c *HandlerCompo) HandlerWithPanic() {
+	panic("handler panicked")
+}
+
+func (c *HandlerCompo) HandlerWithBadReturn() int {
+	return 42
+}
+
 func (c *HandlerCompo) Render() string {
 	return `<h1>Handlers</h1>`
 }
 
+type CompoEventCall struct {
+	Offset int
+
+	selected int
+	kind     string
+	button   int
+	searches int
+}
+
+func (c *CompoEventCall) Render() string {
+	return `
+<div>
+    <button onclick="Select({{.Offset}}, 'primary')">A</button>
+    <button onclick="Select({{.Offset}} , &quot;second, ary&quot;)" ondblclick="Open">B</button>
+    <input onkeyup="Search|throttle:1h|key:Enter|key:Escape" onsubmit="Search|prevent|stop|debounce:300" />
+    <button onclick="Select('abc', 'primary')">C</button>
+    <button onclick="Select(1, 'click')" ondblclick="Select(2, 'dblclick')">D</button>
+    <input onkeydown="Search|throttle:1h|key:Enter" onkeyup="Search|throttle:1h" />
+</div>
+    `
+}
+
+func (c *CompoEventCall) Search(e KeyboardEvent) {
+	c.searches++
+}
+
+func (c *CompoEventCall) Select(id int, kind string, e MouseEvent) {
+	c.selected = id
+	c.kind = kind
+	c.button = e.Button
+}
+
+type CompoEventCallArg struct {
+	Kind string
+
+	kind string
+}
+
+func (c *CompoEventCallArg) Render() string {
+	return `
+<div>
+    <button onclick="Select({{arg .Kind}})">A</button>
+</div>
+    `
+}
+
+func (c *CompoEventCallArg) Select(kind string) {
+	c.kind = kind
+}
+
+type CompoEventCallMalformed struct {
+	Name string
+}
+
+func (c *CompoEventCallMalformed) Render() string {
+	return `
+<div>
+    <button onclick="Select('{{.Name}}', 2)">A</button>
+</div>
+    `
+}
+
+func (c *CompoEventCallMalformed) Select(name string, n int) {}
+
 func init() {
 	Register(&HandlerCompo{})
+	Register(&CompoEventCall{})
+	Register(&CompoEventCallArg{})
+	Register(&CompoEventCallMalformed{})
 }
 
 func TestCallComponentMethod(t *testing.T) {
@@ -51,7 +160,7 @@ func TestCallComponentMethod(t *testing.T) {
 	// Call without arg.
 	cv := reflect.ValueOf(c)
 	mv := cv.MethodByName("HandlerWithoutArg")
-	if err := callComponentMethod(mv, ""); err != nil {
+	if err := callComponentMethod(mv, EventContext{}, nil, ""); err != nil {
 		t.Fatal(err)
 	}
 	if !c.isCalledWithNoArg {
@@ -61,7 +170,7 @@ func TestCallComponentMethod(t *testing.T) {
 	// Call with single arg.
 	arg := `{"Number": 42, "String": "maxoo"}`
 	mv = cv.MethodByName("HandlerWitSingleArg")
-	if err = callComponentMethod(mv, arg); err != nil {
+	if err = callComponentMethod(mv, EventContext{}, nil, arg); err != nil {
 		t.Fatal(err)
 	}
 	if !c.isCalledWithSingleArg {
@@ -76,28 +185,68 @@ func TestCallComponentMethod(t *testing.T) {
 
 	// Call with bad JSON.
 	arg = `{"Number: 42, "String": "maxoo"}`
-	if err = callComponentMethod(mv, arg); err == nil {
+	if err = callComponentMethod(mv, EventContext{}, nil, arg); err == nil {
 		t.Error("err should not be nil")
 	}
 	t.Log(err)
 
 	// Call with multiple args.
 	mv = cv.MethodByName("HandlerWitMultipleArg")
-	if err = callComponentMethod(mv, arg); err == nil {
+	if err = callComponentMethod(mv, EventContext{}, nil, arg); err == nil {
 		t.Error("err should not be nil")
 	}
 	t.Log(err)
 }
 
-func TestGetPipedValue(t *testing.T) {
+func TestCallComponentMethodStaticArgs(t *testing.T) {
+	c := &HandlerCompo{}
+	mv := reflect.ValueOf(c).MethodByName("HandlerWithStaticArgs")
+
+	if err := callComponentMethod(mv, EventContext{}, []string{"21", "21", `{"String": "maxoo"}`}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Struct.Number != 42 || c.Struct.String != "maxoo" {
+		t.Error("c.Struct should be {42 maxoo}:", c.Struct)
+	}
+
+	tests := [][]string{
+		{"abc", "21", "{}"},
+		{"21", "", "{}"},
+		{"21", "21", "{"},
+		{"1.5", "21", "{}"},
+	}
+
+	for _, args := range tests {
+		c.Struct = FuncArg{}
+
+		err := callComponentMethod(mv, EventContext{Handler: "HandlerWithStaticArgs"}, args, "")
+		if err == nil {
+			t.Errorf("%q: err should not be nil", args)
+		}
+		t.Log(err)
+
+		if c.Struct.Number != 0 {
+			t.Errorf("%q: handler should not be called", args)
+		}
+	}
+}
+
+func TestSetPipedValue(t *testing.T) {
 	c := &HandlerCompo{
 		String: "Hello",
+		Slice:  []FuncArg{{}, {Number: 21}},
 	}
 	v := reflect.ValueOf(c).Elem()
 
+	var fv reflect.Value
+	get := func(v reflect.Value) error {
+		fv = v
+		return nil
+	}
+
 	// Direct path.
-	fv, err := getPipedValue(v, strings.Split("String", "."))
-	if err != nil {
+	if err := setPipedValue(v, strings.Split("String", "."), get); err != nil {
 		t.Fatal(err)
 	}
 	if k := fv.Kind(); k != reflect.String {
@@ -105,7 +254,7 @@ func TestGetPipedValue(t *testing.T) {
 	}
 
 	// Path to struct ptr.
-	if fv, err = getPipedValue(v, strings.Split("StructPtr.Number", ".")); err != nil {
+	if err := setPipedValue(v, strings.Split("StructPtr.Number", "."), get); err != nil {
 		t.Fatal(err)
 	}
 	if k := fv.Kind(); k != reflect.Int {
@@ -113,7 +262,7 @@ func TestGetPipedValue(t *testing.T) {
 	}
 
 	// Path to struct.
-	if fv, err = getPipedValue(v, strings.Split("Struct.Number", ".")); err != nil {
+	if err := setPipedValue(v, strings.Split("Struct.Number", "."), get); err != nil {
 		t.Fatal(err)
 	}
 	if k := fv.Kind(); k != reflect.Int {
@@ -121,7 +270,7 @@ func TestGetPipedValue(t *testing.T) {
 	}
 
 	// Path to map.
-	if fv, err = getPipedValue(v, strings.Split("Map.Number", ".")); err != nil {
+	if err := setPipedValue(v, strings.Split("Map.Number", "."), get); err != nil {
 		t.Fatal(err)
 	}
 	if k := fv.Kind(); k != reflect.Int {
@@ -129,31 +278,115 @@ func TestGetPipedValue(t *testing.T) {
 	}
 }
 
-func TestGetPipedValueErrors(t *testing.T) {
-	c := &HandlerCompo{}
+func TestSetPipedValueWrite(t *testing.T) {
+	c := &HandlerCompo{
+		Slice:     []FuncArg{{}, {Number: 21}},
+		StructMap: map[string]FuncArg{"max": {Number: 21, String: "Maxence"}},
+	}
+	v := reflect.ValueOf(c).Elem()
+
+	set := func(value interface{}) func(reflect.Value) error {
+		return func(v reflect.Value) error {
+			v.Set(reflect.ValueOf(value))
+			return nil
+		}
+	}
+
+	// Slice index.
+	if err := setPipedValue(v, strings.Split("Slice.1.Number", "."), set(42)); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.Slice[1].Number; n != 42 {
+		t.Error("c.Slice[1].Number should be 42:", n)
+	}
+
+	// Array index.
+	if err := setPipedValue(v, strings.Split("Array.0.String", "."), set("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if s := c.Array[0].String; s != "hello" {
+		t.Error("c.Array[0].String should be hello:", s)
+	}
+
+	// Map entry.
+	if err := setPipedValue(v, strings.Split("Map.answer", "."), set(42)); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.Map["answer"]; n != 42 {
+		t.Error(`c.Map["answer"] should be 42:`, n)
+	}
+
+	// Non-string map key.
+	if err := setPipedValue(v, strings.Split("IntMap.42", "."), set("answer")); err != nil {
+		t.Fatal(err)
+	}
+	if s := c.IntMap[42]; s != "answer" {
+		t.Error("c.IntMap[42] should be answer:", s)
+	}
+
+	// Map of struct.
+	if err := setPipedValue(v, strings.Split("StructMap.max.Number", "."), set(42)); err != nil {
+		t.Fatal(err)
+	}
+	if a := c.StructMap["max"]; a.Number != 42 || a.String != "Maxence" {
+		t.Error(`c.StructMap["max"] should be {42 Maxence}:`, a)
+	}
+
+	// Failed write is not stored.
+	errWrite := errors.New("write failed")
+	if err := setPipedValue(v, strings.Split("StructMap.jonhy.Number", "."), func(reflect.Value) error {
+		return errWrite
+	}); err != errWrite {
+		t.Error("err should be errWrite:", err)
+	}
+	if _, stored := c.StructMap["jonhy"]; stored {
+		t.Error(`c.StructMap["jonhy"] should not be stored`)
+	}
+}
+
+func TestSetPipedValueErrors(t *testing.T) {
+	c := &HandlerCompo{
+		Slice: []FuncArg{{}},
+	}
 	v := reflect.ValueOf(c).Elem()
 
+	get := func(reflect.Value) error {
+		return nil
+	}
+
 	// Invalid path.
-	_, err := getPipedValue(v, strings.Split(".Number", "."))
+	err := setPipedValue(v, strings.Split(".Number", "."), get)
 	if err == nil {
 		t.Error("err should not be nil")
 	}
 	t.Log(err)
 
 	// Nonexistent field.
-	if _, err = getPipedValue(v, strings.Split("Foo", ".")); err == nil {
+	if err = setPipedValue(v, strings.Split("Foo", "."), get); err == nil {
 		t.Error("err should not be nil")
 	}
 	t.Log(err)
 
-	// Path to invalid map.
-	if _, err = getPipedValue(v, strings.Split("InvalidMap.1", ".")); err == nil {
+	// Invalid map key.
+	if err = setPipedValue(v, strings.Split("IntMap.one", "."), get); err == nil {
+		t.Error("err should not be nil")
+	}
+	t.Log(err)
+
+	// Invalid index.
+	if err = setPipedValue(v, strings.Split("Slice.first", "."), get); err == nil {
+		t.Error("err should not be nil")
+	}
+	t.Log(err)
+
+	// Index out of range.
+	if err = setPipedValue(v, strings.Split("Slice.1", "."), get); err == nil {
 		t.Error("err should not be nil")
 	}
 	t.Log(err)
 
 	// Invalid pipeline source.
-	if _, err = getPipedValue(v, strings.Split("String.max", ".")); err == nil {
+	if err = setPipedValue(v, strings.Split("String.max", "."), get); err == nil {
 		t.Error("err should not be nil")
 	}
 	t.Log(err)
@@ -211,31 +444,75 @@ func TestHandleEvent(t *testing.T) {
 	defer Dismount(c)
 
 	// Method.
-	HandleEvent(root.ID, "HandlerWithoutArg", "")
+	HandleEvent(root.ID, "", "HandlerWithoutArg", "")
 	if !c.isCalledWithNoArg {
 		t.Error("HandlerWithoutArg should have been called")
 	}
 
 	// Field.
-	HandleEvent(root.ID, "String", `{"Value": "hello"}`)
+	HandleEvent(root.ID, "", "String", `{"Value": "hello"}`)
 	if c.String != "hello" {
 		t.Error("c.String should be hello:", c.String)
 	}
 
-	HandleEvent(root.ID, "Struct.Number", `{"Value": "42"}`)
+	HandleEvent(root.ID, "", "Struct.Number", `{"Value": "42"}`)
 	if c.Struct.Number != 42 {
 		t.Error("c.Struct.Number should be 42:", c.Struct.Number)
 	}
 
-	HandleEvent(root.ID, "StructPtr.Number", `{"Value": "42"}`)
+	HandleEvent(root.ID, "", "StructPtr.Number", `{"Value": "42"}`)
 	if c.StructPtr.Number != 42 {
 		t.Error("c.StructPtr.Number should be 42:", c.StructPtr.Number)
 	}
 
-	HandleEvent(root.ID, "Struct", `{"Value": "{\"Number\":21}"}`)
+	HandleEvent(root.ID, "", "Struct", `{"Value": "{\"Number\":21}"}`)
 	if c.Struct.Number != 21 {
 		t.Error("c.Struct.Number should be 21:", c.Struct.Number)
 	}
+
+	HandleEvent(root.ID, "", "StructMap.max.String", `{"Value": "Maxence"}`)
+	if s := c.StructMap["max"].String; s != "Maxence" {
+		t.Error(`c.StructMap["max"].String should be Maxence:`, s)
+	}
+}
+
+func TestHandleEventNotAllowed(t *testing.T) {
+	c := &HandlerCompo{}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	// Method which is not listed.
+	err = HandleEvent(root.ID, "", "Render", "")
+	if _, notAllowed := err.(*HandlerNotAllowedError); !notAllowed {
+		t.Error("err should be a *HandlerNotAllowedError:", err)
+	}
+	t.Log(err)
+
+	// Field which is not tagged.
+	err = HandleEvent(root.ID, "", "Map.answer", `{"Value": "42"}`)
+	if _, notAllowed := err.(*HandlerNotAllowedError); !notAllowed {
+		t.Error("err should be a *HandlerNotAllowedError:", err)
+	}
+	if len(c.Map) != 0 {
+		t.Error("c.Map should be empty:", c.Map)
+	}
+
+	// Handler declared by another node.
+	d := &CompoEventCall{}
+
+	if root, err = Mount(d, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(d)
+
+	err = HandleEvent(root.Children[0].ID, "ondblclick", "Open", "")
+	if _, notAllowed := err.(*HandlerNotAllowedError); !notAllowed {
+		t.Error("err should be a *HandlerNotAllowedError:", err)
+	}
 }
 
 func TestHandleEventPanic(t *testing.T) {
@@ -251,16 +528,45 @@ func TestHandleEventPanic(t *testing.T) {
 	defer Dismount(c)
 
 	// Method.
-	HandleEvent(root.ID, "", "")
+	HandleEvent(root.ID, "", "", "")
 	t.Error("should have panic")
 }
 
+func TestHandleEventContext(t *testing.T) {
+	c := &HandlerCompo{}
+	ctx := uuid.NewV1()
+
+	root, err := Mount(c, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	if err = HandleEvent(root.ID, "", "HandlerWithContext", `{"Number": 42}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.ctx.NodeID != root.ID || c.ctx.ContextID != ctx || c.ctx.Handler != "HandlerWithContext" || c.ctx.Source != root {
+		t.Errorf("bad event context: %+v", c.ctx)
+	}
+
+	if c.Struct.Number != 42 {
+		t.Error("c.Struct.Number should be 42:", c.Struct.Number)
+	}
+
+	if err = HandleEvent(root.ID, "", "HandlerWithError", ""); err != errHandler {
+		t.Error("err should be errHandler:", err)
+	}
+}
+
 func TestHandleEventError(t *testing.T) {
 	c := &HandlerCompo{}
 	ctx := uuid.NewV1()
 
 	// Not mounted.
-	HandleEvent(uuid.NewV1(), "HandlerWitMultipleArg", "")
+	if err := HandleEvent(uuid.NewV1(), "", "HandlerWitMultipleArg", ""); err == nil {
+		t.Error("err should not be nil")
+	}
 
 	root, err := Mount(c, ctx)
 	if err != nil {
@@ -269,9 +575,381 @@ func TestHandleEventError(t *testing.T) {
 	defer Dismount(c)
 
 	// Method.
-	HandleEvent(root.ID, "HandlerWitMultipleArg", "")
+	if err = HandleEvent(root.ID, "", "HandlerWitMultipleArg", ""); err == nil {
+		t.Error("err should not be nil")
+	}
+	t.Log(err)
+
+	if err = HandleEvent(root.ID, "", "HandlerWithBadReturn", ""); err == nil {
+		t.Error("err should not be nil")
+	}
+	t.Log(err)
 
 	// Field.
-	HandleEvent(root.ID, "Hello", `{"Value": "hello"}`)
-	HandleEvent(root.ID, "String", `{"Value": hello"}`)
+	if err = HandleEvent(root.ID, "", "Hello", `{"Value": "hello"}`); err == nil {
+		t.Error("err should not be nil")
+	}
+	t.Log(err)
+
+	if err = HandleEvent(root.ID, "", "String", `{"Value": hello"}`); err == nil {
+		t.Error("err should not be nil")
+	}
+	t.Log(err)
+}
+
+func TestParseEventCall(t *testing.T) {
+	tests := []struct {
+		value   string
+		handler string
+		args    []string
+	}{
+		{
+			value:   "OnClick",
+			handler: "OnClick",
+		},
+		{
+			value:   "Struct.Number",
+			handler: "Struct.Number",
+		},
+		{
+			value:   "OnClick()",
+			handler: "OnClick",
+		},
+		{
+			value:   "Select(42, 'primary')",
+			handler: "Select",
+			args:    []string{"42", "primary"},
+		},
+		{
+			value:   ` Select ( 'it\'s, ok' , "", true ) `,
+			handler: "Select",
+			args:    []string{"it's, ok", "", "true"},
+		},
+	}
+
+	for _, test := range tests {
+		call, err := parseEventCall(test.value)
+		if err != nil {
+			t.Errorf("%v: %v", test.value, err)
+			continue
+		}
+
+		if call.Handler != test.handler {
+			t.Errorf("%v: handler should be %v: %v", test.value, test.handler, call.Handler)
+		}
+
+		if !reflect.DeepEqual(call.Args, test.args) {
+			t.Errorf("%v: args should be %q: %q", test.value, test.args, call.Args)
+		}
+	}
+}
+
+func TestParseEventCallError(t *testing.T) {
+	tests := []string{
+		"",
+		"Select(",
+		"Select(1, 2",
+		"Select(1, 2))",
+		"Select(1,, 2)",
+		"Select(1, 2,)",
+		"Select('O'Brien', 2)",
+		"Select('abc' def)",
+		"Select(ab'c')",
+		"Select('abc)",
+		"Select(f(1))",
+		"(1, 2)",
+		"Sel ect",
+		"Search|foo",
+		"Search|debounce:abc",
+		"Search|throttle:",
+		"Search|key:",
+		"Search|",
+		"Select('a') | prevent",
+	}
+
+	for _, test := range tests[:len(tests)-1] {
+		if _, err := parseEventCall(test); err == nil {
+			t.Errorf("%q: err should not be nil", test)
+		} else {
+			t.Log(err)
+		}
+	}
+
+	if _, err := parseEventCall(tests[len(tests)-1]); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMountMalformedEventCall(t *testing.T) {
+	c := &CompoEventCallMalformed{Name: "O'Brien"}
+
+	if _, err := Mount(c, uuid.NewV1()); err == nil {
+		t.Error("err should not be nil")
+		Dismount(c)
+	} else {
+		t.Log(err)
+	}
+}
+
+func TestHandleEventQuotedArg(t *testing.T) {
+	kinds := []string{
+		`O'Brien`,
+		`"Max"`,
+		`back\slash\'`,
+		`<b>&amp;</b>`,
+		`a, b) | prevent`,
+	}
+
+	for _, kind := range kinds {
+		c := &CompoEventCallArg{Kind: kind}
+
+		root, err := Mount(c, uuid.NewV1())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = HandleEvent(root.Children[0].ID, "onclick", "Select", `{}`); err != nil {
+			t.Error(err)
+		}
+
+		if c.kind != kind {
+			t.Errorf("c.kind should be %q: %q", kind, c.kind)
+		}
+		Dismount(c)
+	}
+}
+
+func TestHandleEventStaticArgs(t *testing.T) {
+	c := &CompoEventCall{Offset: 42}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	first := root.Children[0]
+	second := root.Children[1]
+
+	if handler := first.Attributes["onclick"]; handler != "Select" {
+		t.Error("onclick attribute should be Select:", handler)
+	}
+
+	if err = HandleEvent(first.ID, "onclick", "Select", `{"button": 2}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.selected != 42 || c.kind != "primary" || c.button != 2 {
+		t.Errorf("bad call: %v %v %v", c.selected, c.kind, c.button)
+	}
+
+	if err = HandleEvent(second.ID, "onclick", "Select", `{}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.kind != "second, ary" {
+		t.Error("c.kind should be second, ary:", c.kind)
+	}
+
+	// Static arguments are updated without syncs.
+	c.Offset = 21
+
+	syncs, err := Synchronize(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 0 {
+		t.Error("l should be 0:", l)
+	}
+
+	if err = HandleEvent(first.ID, "onclick", "Select", `{}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.selected != 21 {
+		t.Error("c.selected should be 21:", c.selected)
+	}
+
+	// Static arguments which do not convert are not called with a zero value.
+	if err = HandleEvent(root.Children[3].ID, "onclick", "Select", `{}`); err == nil {
+		t.Error("err should not be nil")
+	}
+	t.Log(err)
+
+	if c.selected != 21 {
+		t.Error("c.selected should be 21:", c.selected)
+	}
+}
+
+func TestHandleEventAttribute(t *testing.T) {
+	c := &CompoEventCall{}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	button := root.Children[4]
+
+	if err = HandleEvent(button.ID, "ondblclick", "Select", `{}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.selected != 2 || c.kind != "dblclick" {
+		t.Errorf("bad dblclick call: %v %v", c.selected, c.kind)
+	}
+
+	if err = HandleEvent(button.ID, "onclick", "Select", `{}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.selected != 1 || c.kind != "click" {
+		t.Errorf("bad click call: %v %v", c.selected, c.kind)
+	}
+
+	// The attribute must call the handler.
+	err = HandleEvent(button.ID, "onclick", "Search", `{}`)
+	if _, notAllowed := err.(*HandlerNotAllowedError); !notAllowed {
+		t.Error("err should be a *HandlerNotAllowedError:", err)
+	}
+}
+
+func TestHandleEventModifiersByAttribute(t *testing.T) {
+	c := &CompoEventCall{}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	input := root.Children[5]
+
+	// The key modifier of onkeydown does not filter onkeyup.
+	if err = HandleEvent(input.ID, "onkeyup", "Search", `{"key": "a"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.searches != 1 {
+		t.Error("c.searches should be 1:", c.searches)
+	}
+
+	if err = HandleEvent(input.ID, "onkeydown", "Search", `{"key": "a"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.searches != 1 {
+		t.Error("c.searches should be 1:", c.searches)
+	}
+
+	// Each event attribute is throttled separately.
+	if err = HandleEvent(input.ID, "onkeydown", "Search", `{"key": "Enter"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.searches != 2 {
+		t.Error("c.searches should be 2:", c.searches)
+	}
+
+	if err = HandleEvent(input.ID, "onkeyup", "Search", `{"key": "b"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.searches != 2 {
+		t.Error("c.searches should be 2:", c.searches)
+	}
+}
+
+func TestParseEventModifiers(t *testing.T) {
+	call, err := parseEventCall(`Select('a|b') | prevent|stop |debounce:300| throttle:1s|key:Enter|key:Escape`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if call.Handler != "Select" || len(call.Args) != 1 || call.Args[0] != "a|b" {
+		t.Errorf("bad call: %+v", call)
+	}
+
+	expected := EventModifiers{
+		PreventDefault:  true,
+		StopPropagation: true,
+		Debounce:        300 * time.Millisecond,
+		Throttle:        time.Second,
+		Keys:            []string{"Enter", "Escape"},
+	}
+
+	if !reflect.DeepEqual(call.Modifiers, expected) {
+		t.Errorf("modifiers should be %+v: %+v", expected, call.Modifiers)
+	}
+
+	if s := call.Modifiers.String(); s != "|prevent|stop|debounce:300ms|throttle:1s|key:Enter|key:Escape" {
+		t.Error("bad modifiers string:", s)
+	}
+}
+
+func TestNodeMarkupEventModifiers(t *testing.T) {
+	c := &CompoEventCall{}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	input := root.Children[2]
+
+	if attr := input.Attributes["onsubmit"]; attr != "Search|prevent|stop|debounce:300ms" {
+		t.Error("bad onsubmit attribute:", attr)
+	}
+
+	m := Markup(c)
+	t.Log(m)
+
+	if !strings.Contains(m, `'onsubmit', 'Search', this, event, {&#34;prevent&#34;:true,&#34;stop&#34;:true,&#34;debounce&#34;:300})`) {
+		t.Error("markup should contain onsubmit modifiers")
+	}
+
+	if !strings.Contains(m, `'onclick', 'Select', this, event)`) {
+		t.Error("markup should contain onclick without modifiers")
+	}
+}
+
+func TestHandleEventModifiers(t *testing.T) {
+	c := &CompoEventCall{}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	input := root.Children[2]
+
+	// Filtered by key.
+	if err = HandleEvent(input.ID, "onkeyup", "Search", `{"key": "a"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.searches != 0 {
+		t.Error("c.searches should be 0:", c.searches)
+	}
+
+	if err = HandleEvent(input.ID, "onkeyup", "Search", `{"key": "Enter"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.searches != 1 {
+		t.Error("c.searches should be 1:", c.searches)
+	}
+
+	// Throttled.
+	if err = HandleEvent(input.ID, "onkeyup", "Search", `{"key": "Escape"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.searches != 1 {
+		t.Error("c.searches should be 1:", c.searches)
+	}
 }
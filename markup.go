@@ -2,4 +2,54 @@
 // interfaces.
 //
 // https://github.com/murlokswarm/markup
+//
+// Events
+//
+// Event handlers are wired in the output of Markup as:
+//  onclick="CallEvent('<node id>', 'onclick', '<handler>', this, event)"
+//  onkeyup="CallEvent('<node id>', 'onkeyup', '<handler>', this, event, <modifiers>)"
+// Drivers implement CallEvent to send the node id, the event attribute, the
+// handler and the JSON encoded payload of the event to HandleEvent. The event
+// attribute designates the call, with its static arguments and modifiers,
+// among the ones of the node. Modifiers are passed when the
+// event attribute declares some, as documented on EventModifiers: drivers
+// apply them before sending the event. The payload depends on the
+// event attribute:
+//  onclick, ondblclick, oncontextmenu,
+//  onmousedown, onmouseup, onmousemove,
+//  onmouseenter, onmouseleave, onmouseover, onmouseout  MouseEvent
+//  onkeydown, onkeyup, onkeypress                       KeyboardEvent
+//  onwheel                                              WheelEvent
+//  onchange, oninput                                    ChangeEvent
+//  onsubmit, onreset                                    FormEvent
+//  ondrag, ondragstart, ondragend, ondragenter,
+//  ondragleave, ondragover, ondrop                      DragEvent
+//  onfocus, onblur, onfocusin, onfocusout               FocusEvent
+// Payload fields are named like the properties of the DOM events. Drivers must
+// send every field of the payload type; a field which is not available on the
+// native side is sent with its zero value.
+//
+// Event attributes on component tags bind the events that components emit to
+// their parent with Emit:
+//  <Dialog onconfirm="Save" />
+//
+// Properties
+//
+// Native element properties, like the value of an input or the checked state
+// of a checkbox, are not attributes. Markup writes them in a data-murlok-props
+// attribute, which contains an HTML escaped JSON object:
+//  <input data-murlok-id="<node id>" data-murlok-props="{&#34;value&#34;:&#34;Max&#34;}"/>
+// Drivers must read it when they insert the markup and set each entry as a
+// property of the element, like element[name] = value, rather than as an
+// attribute. Values are strings: boolean properties are "true" or "false".
+// The Properties of syncs are applied the same way.
+//
+// Bindings
+//
+// The bind attribute binds the value of an element to a component field:
+//  <input type="email" bind="Form.Email" />
+// It is expanded into the value property of the element, or its checked
+// property for checkboxes and radio buttons, and an oninput or onchange
+// handler which writes the value in the field. Components implementing
+// Validator validate the values before they are written.
 package markup
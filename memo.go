@@ -0,0 +1,45 @@
+package markup
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Memoizer is the interface that wraps Memoize method.
+// Memoize reports whether the component can skip rendering when its exported
+// state did not change since its last render. Components are memoized by
+// default. Components which render a hidden state, like unexported fields or
+// global variables, should return false.
+type Memoizer interface {
+	Memoize() bool
+}
+
+// fingerprint returns a fingerprint of the exported state of c. memoized is
+// false when c opted out of memoization or when its state cannot be
+// fingerprinted.
+func fingerprint(c Componer) (f string, memoized bool) {
+	if m, isMemoizer := c.(Memoizer); isMemoizer && !m.Memoize() {
+		return
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	return string(b), true
+}
+
+// upToDate reports whether compo rendered its current state: its exported
+// state and the values it consumes did not change since its last render.
+func upToDate(compo *component, f string, memoized bool) bool {
+	if !memoized || f != compo.Fingerprint {
+		return false
+	}
+
+	for t, consumed := range compo.Consumed {
+		if value, _ := providedValue(consumed.Provider, t); !reflect.DeepEqual(value, consumed.Value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package markup
+
+import (
+	"testing"
+
+	"github.com/satori/go.uuid"
+)
+
+type CompoMemo struct {
+	Name      string
+	ChildName string
+	renders   int
+}
+
+func (c *CompoMemo) Render() string {
+	c.renders++
+	return `
+<div>
+    <h1>{{.Name}}</h1>
+    <CompoMemoChild Name="{{.ChildName}}" />
+</div>
+    `
+}
+
+type CompoMemoChild struct {
+	Name    string
+	renders int
+}
+
+func (c *CompoMemoChild) Render() string {
+	c.renders++
+	return `<p>{{.Name}}</p>`
+}
+
+type CompoMemoHidden struct {
+	name string
+}
+
+func (c *CompoMemoHidden) Render() string {
+	return `<p>{{.Name}}</p>`
+}
+
+func (c *CompoMemoHidden) Name() string {
+	return c.name
+}
+
+func (c *CompoMemoHidden) Memoize() bool {
+	return false
+}
+
+func init() {
+	Register(&CompoMemo{})
+	Register(&CompoMemoChild{})
+	Register(&CompoMemoHidden{})
+}
+
+func TestSynchronizeMemoized(t *testing.T) {
+	c := &CompoMemo{Name: "Maxence"}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	child := root.Children[1].Component.(*CompoMemoChild)
+
+	syncs, err := Synchronize(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 0 {
+		t.Error("l should be 0:", l)
+	}
+
+	if c.renders != 1 {
+		t.Error("c should not be rendered again:", c.renders)
+	}
+
+	c.Name = "Jonhy"
+
+	if _, err = Synchronize(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.renders != 2 {
+		t.Error("c should be rendered again:", c.renders)
+	}
+
+	if child.renders != 1 {
+		t.Error("child should not be rendered again:", child.renders)
+	}
+
+	c.ChildName = "Max"
+
+	if _, err = Synchronize(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if child.renders != 2 {
+		t.Error("child should be rendered again:", child.renders)
+	}
+}
+
+func TestSynchronizeNotMemoized(t *testing.T) {
+	c := &CompoMemoHidden{name: "Maxence"}
+
+	if _, err := Mount(c, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	c.name = "Jonhy"
+
+	syncs, err := Synchronize(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 1 {
+		t.Fatal("l should be 1:", l)
+	}
+
+	if s := syncs[0]; s.Scope != TextSync || s.Node.Text != "Jonhy" {
+		t.Error("s should be a TextSync of Jonhy")
+	}
+}
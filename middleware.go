@@ -0,0 +1,58 @@
+package markup
+
+var (
+	middlewares = []Middleware{recoverMiddleware}
+)
+
+// Event describes an event dispatched by HandleEvent.
+// Node is the node which sent the event and Component the component whose
+// handler is called: the component that mounted Node, or the parent of the
+// emitting component for the events sent with Emit, whose Node is the root of
+// the emitting component. Attribute is the event attribute which calls
+// Handler, the method or the field designated by the event, and Call its call,
+// as declared in the markup when Declared is true.
+// Args is the JSON encoded payload of the event.
+type Event struct {
+	Node      *Node
+	Component Componer
+	Attribute string
+	Handler   string
+	Call      EventCall
+	Declared  bool
+	Args      string
+}
+
+// Handler is a function which dispatches an event.
+type Handler func(e Event) error
+
+// Middleware is a function which wraps the dispatch of events, like logging or
+// authorization. It returns a Handler which calls next to continue the
+// dispatch, or which returns without calling it to short-circuit the
+// dispatch.
+type Middleware func(next Handler) Handler
+
+// Use registers m to wrap the dispatch of the events handled by HandleEvent.
+// Middlewares are called in the order they are registered. The first
+// middleware is a default one which recovers the panics of the handlers and
+// of the middlewares registered after it, and returns them as errors.
+func Use(m Middleware) {
+	middlewares = append(middlewares, m)
+}
+
+// handler returns the dispatch of events wrapped by the middlewares.
+func handler() Handler {
+	h := Handler(dispatchEvent)
+
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+func recoverMiddleware(next Handler) Handler {
+	return func(e Event) error {
+		return protect(func() error {
+			return next(e)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package markup
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/satori/go.uuid"
+)
+
+func TestUse(t *testing.T) {
+	defer func(m []Middleware) { middlewares = m }(middlewares)
+
+	c := &HandlerCompo{}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	var calls []string
+	errDenied := errors.New("denied")
+
+	Use(func(next Handler) Handler {
+		return func(e Event) error {
+			calls = append(calls, "log "+e.Handler)
+			return next(e)
+		}
+	})
+
+	Use(func(next Handler) Handler {
+		return func(e Event) error {
+			if e.Handler == "HandlerWithError" {
+				return errDenied
+			}
+
+			if e.Node != root || e.Component != c {
+				t.Error("event should come from the root of c:", e.Node, e.Component)
+			}
+
+			if e.Args != `{"Value": "hello"}` {
+				t.Error("bad args:", e.Args)
+			}
+			return next(e)
+		}
+	})
+
+	if err = HandleEvent(root.ID, "", "String", `{"Value": "hello"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.String != "hello" {
+		t.Error("c.String should be hello:", c.String)
+	}
+
+	// Short-circuit.
+	if err = HandleEvent(root.ID, "", "HandlerWithError", ""); err != errDenied {
+		t.Error("err should be errDenied:", err)
+	}
+
+	if l := len(calls); l != 2 {
+		t.Fatal("l should be 2:", l)
+	}
+
+	if calls[0] != "log String" || calls[1] != "log HandlerWithError" {
+		t.Error("bad calls:", calls)
+	}
+}
+
+func TestUseRecover(t *testing.T) {
+	c := &HandlerCompo{}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	if err = HandleEvent(root.ID, "", "HandlerWithPanic", ""); err == nil {
+		t.Error("err should not be nil")
+	}
+	t.Log(err)
+}
@@ -2,10 +2,12 @@ package markup
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"html"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/murlokswarm/log"
 	"github.com/satori/go.uuid"
@@ -44,10 +46,16 @@ type Node struct {
 	Tag        string
 	Text       string
 	Attributes AttributeMap
+	Properties AttributeMap
+	Events     map[string]EventCall
 	Component  Componer
 	Mount      Componer
 	Parent     *Node
 	Children   []*Node
+
+	compo            *component
+	nativeProperties AttributeMap
+	throttled        map[string]time.Time
 }
 
 // NodeType represents the type of the node.
@@ -74,7 +82,7 @@ func (n *Node) markup(indent int) string {
 	}
 
 	if n.Type == ComponentNode {
-		if n.Component == nil {
+		if n.compo == nil {
 			b.WriteString(indt)
 			b.WriteString("<!-- ")
 			b.WriteString(n.Tag)
@@ -82,7 +90,7 @@ func (n *Node) markup(indent int) string {
 			return b.String()
 		}
 
-		b.WriteString(Root(n.Component).markup(indent))
+		b.WriteString(n.compo.Root.markup(indent))
 		return b.String()
 	}
 
@@ -95,16 +103,34 @@ func (n *Node) markup(indent int) string {
 	b.WriteRune('"')
 
 	for name, value := range n.Attributes {
+		if isAbsentAttribute(name, value) {
+			continue
+		}
+
 		b.WriteRune(' ')
 
 		if isMarkupEvent(name) {
+			call, declared := n.Events[name]
+			if !declared {
+				call, _ = parseEventCall(value)
+			}
+
 			b.WriteString(name)
 			b.WriteString(`="CallEvent('`)
 			b.WriteString(n.ID.String())
 			b.WriteString("', '")
-			b.WriteString(value)
-			b.WriteString(`', this, event)"`)
+			b.WriteString(name)
+			b.WriteString("', '")
+			b.WriteString(call.Handler)
+			b.WriteString(`', this, event`)
+
+			if !call.Modifiers.isZero() {
+				modifiers, _ := json.Marshal(call.Modifiers)
+				b.WriteString(", ")
+				b.WriteString(html.EscapeString(string(modifiers)))
+			}
 
+			b.WriteString(`)"`)
 			continue
 		}
 
@@ -132,6 +158,13 @@ func (n *Node) markup(indent int) string {
 		b.WriteRune('"')
 	}
 
+	if len(n.Properties) != 0 {
+		props, _ := json.Marshal(n.Properties)
+		b.WriteString(` data-murlok-props="`)
+		b.WriteString(html.EscapeString(string(props)))
+		b.WriteRune('"')
+	}
+
 	if _, selfClosing := selfClosingTags[n.Tag]; selfClosing {
 		b.WriteString("/>")
 		return b.String()
@@ -1,6 +1,7 @@
 package markup
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/satori/go.uuid"
@@ -34,3 +35,24 @@ func TestNodeMarkupHrefError(t *testing.T) {
 	}
 	t.Log(n.Markup())
 }
+
+func TestNodeMarkupBooleanAttributes(t *testing.T) {
+	n := Node{
+		ID:  uuid.NewV1(),
+		Tag: "input",
+		Attributes: AttributeMap{
+			"checked":  "false",
+			"disabled": "true",
+			"value":    "false",
+		},
+	}
+	m := n.Markup()
+
+	if strings.Contains(m, "checked") {
+		t.Error("markup should not contain checked:", m)
+	}
+
+	if !strings.Contains(m, `disabled="true"`) || !strings.Contains(m, `value="false"`) {
+		t.Error("markup should contain disabled and value:", m)
+	}
+}
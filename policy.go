@@ -0,0 +1,77 @@
+package markup
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/satori/go.uuid"
+)
+
+// handlerTag is the struct tag which marks the fields that can be written by
+// events without being declared in the markup:
+//  type Hello struct {
+//      Name string `markup:"handler"`
+//  }
+const (
+	handlerTag      = "markup"
+	handlerTagValue = "handler"
+)
+
+// EventHandlerer is the interface that wraps EventHandlers method.
+// EventHandlers returns the methods and the fields that can be called or
+// written by events without being declared in the markup of the node which
+// sends them. A field allows the paths which go through it: "Form" allows
+// "Form.Email".
+type EventHandlerer interface {
+	EventHandlers() []string
+}
+
+// HandlerNotAllowedError is the error returned by HandleEvent when an event
+// designates a handler which is not allowed for its node.
+// Events come from drivers, which can be remote clients: a handler is allowed
+// only when it is declared in an event or a bind attribute of the node, when
+// it is listed by EventHandlers or when it is a field marked with the
+// markup:"handler" tag.
+type HandlerNotAllowedError struct {
+	NodeID    uuid.UUID
+	Component Componer
+	Handler   string
+}
+
+func (e *HandlerNotAllowedError) Error() string {
+	return fmt.Sprintf("%T handler %v is not allowed for node %v", e.Component, e.Handler, e.NodeID)
+}
+
+// allowHandler reports whether c allows handler to be called by the events of
+// the nodes which do not declare it.
+func allowHandler(c Componer, handler string) bool {
+	if h, isHandlerer := c.(EventHandlerer); isHandlerer {
+		for _, allowed := range h.EventHandlers() {
+			if handler == allowed || strings.HasPrefix(handler, allowed+".") {
+				return true
+			}
+		}
+	}
+
+	t := reflect.TypeOf(c)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	f, isField := t.FieldByName(strings.SplitN(handler, ".", 2)[0])
+	if !isField {
+		return false
+	}
+
+	for _, v := range strings.Split(f.Tag.Get(handlerTag), ",") {
+		if v == handlerTagValue {
+			return true
+		}
+	}
+	return false
+}
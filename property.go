@@ -0,0 +1,82 @@
+package markup
+
+import (
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+)
+
+// propNamespace is the namespace of the attributes which declare a property
+// of an element rather than an attribute:
+//  <input type="text" prop:value="{{.Name}}" />
+// Properties reflect the live state of a native element, like the value of an
+// input or the scroll position of a container. They are sent to the driver
+// only when they differ from the value the element is known to have.
+const propNamespace = "prop"
+
+// ReportProperty records the value of the property name of the element
+// designated by id, as reported by the driver. Drivers should report the
+// properties which change without a sync, like the value of an input which is
+// typed by the user: a property is not sent again when its rendered value
+// already matches the reported one.
+func ReportProperty(id uuid.UUID, name string, value string) error {
+	n, mounted := nodes[id]
+	if !mounted {
+		return errors.Errorf("node with id %v is not mounted", id)
+	}
+
+	if n.nativeProperties == nil {
+		n.nativeProperties = AttributeMap{}
+	}
+	n.nativeProperties[name] = value
+
+	// The element does not show the rendered value anymore: the component is
+	// rendered again on its next synchronization, even when its state did not
+	// change.
+	if rendered, declared := n.Properties[name]; declared && rendered != value {
+		for _, compo := range components[n.Mount] {
+			compo.Fingerprint = ""
+		}
+	}
+	return nil
+}
+
+// setProperties sets the properties of n to props, which are then considered
+// as sent to the driver.
+func setProperties(n *Node, props AttributeMap) {
+	n.Properties = props
+	n.nativeProperties = nil
+
+	if len(props) == 0 {
+		return
+	}
+
+	n.nativeProperties = make(AttributeMap, len(props))
+	for name, value := range props {
+		n.nativeProperties[name] = value
+	}
+}
+
+// syncProperties updates the properties of live with the ones of new and
+// returns the properties to send. A property is not sent when the native
+// element already has its value. A property which is not declared anymore is
+// not sent: the native element keeps its value.
+func syncProperties(live *Node, new *Node) (props AttributeMap) {
+	for name, value := range new.Properties {
+		if native, known := live.nativeProperties[name]; known && native == value {
+			continue
+		}
+
+		if props == nil {
+			props = AttributeMap{}
+		}
+		props[name] = value
+
+		if live.nativeProperties == nil {
+			live.nativeProperties = AttributeMap{}
+		}
+		live.nativeProperties[name] = value
+	}
+
+	live.Properties = new.Properties
+	return
+}
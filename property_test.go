@@ -0,0 +1,117 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/satori/go.uuid"
+)
+
+type CompoProps struct {
+	Name string
+}
+
+func (c *CompoProps) Render() string {
+	return `<input type="text" class="{{.Name}}" prop:value="{{.Name}}" />`
+}
+
+func init() {
+	Register(&CompoProps{})
+}
+
+func TestDecodeProperties(t *testing.T) {
+	n, err := stringToNode(`<input prop:value="Maxence" value="Jonhy" />`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value := n.Properties["value"]; value != "Maxence" {
+		t.Error("value property should be Maxence:", value)
+	}
+
+	if value := n.Attributes["value"]; value != "Jonhy" {
+		t.Error("value attribute should be Jonhy:", value)
+	}
+}
+
+func TestNodeMarkupProperties(t *testing.T) {
+	c := &CompoProps{Name: "Maxence"}
+
+	if _, err := Mount(c, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	m := Markup(c)
+
+	if !strings.Contains(m, `data-murlok-props="{&#34;value&#34;:&#34;Maxence&#34;}"`) {
+		t.Error("markup should contain the properties:", m)
+	}
+}
+
+func TestSynchronizeProperties(t *testing.T) {
+	c := &CompoProps{Name: "Maxence"}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	c.Name = "Jonhy"
+
+	syncs, err := Synchronize(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 1 {
+		t.Fatal("l should be 1:", l)
+	}
+
+	if value := syncs[0].Properties["value"]; value != "Jonhy" {
+		t.Error("value property should be Jonhy:", value)
+	}
+
+	// Typed by the user and reported by the driver.
+	if err = ReportProperty(root.ID, "value", "Max"); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Name = "Max"
+
+	if syncs, err = Synchronize(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 1 {
+		t.Fatal("l should be 1:", l)
+	}
+
+	if l := len(syncs[0].Properties); l != 0 {
+		t.Error("value property should not be sent:", syncs[0].Properties)
+	}
+
+	// The component overrides the typed value.
+	if err = ReportProperty(root.ID, "value", "Maxoo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if syncs, err = Synchronize(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 1 {
+		t.Fatal("l should be 1:", l)
+	}
+
+	if value := syncs[0].Properties["value"]; value != "Max" {
+		t.Error("value property should be Max:", value)
+	}
+}
+
+func TestReportPropertyNotMounted(t *testing.T) {
+	if err := ReportProperty(uuid.NewV1(), "value", "Maxence"); err == nil {
+		t.Error("err should not be nil")
+	}
+}
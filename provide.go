@@ -0,0 +1,150 @@
+package markup
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/murlokswarm/log"
+	"github.com/pkg/errors"
+)
+
+// Provider is the interface that wraps Provide method.
+// Provide returns the values that the component makes available to the
+// components mounted under it. Values are looked up by their type with
+// Consume.
+type Provider interface {
+	Provide() []interface{}
+}
+
+// consumption describes a value consumed by a component.
+type consumption struct {
+	Provider *component
+	Value    interface{}
+}
+
+// Consume sets the value pointed by v to the value of the same type provided
+// by the nearest ancestor of c which implements Provider. It returns false
+// when no ancestor provides such a value.
+// Consume is intended to be called while c is rendered, like from a method
+// used in its template. When the provider is synchronized and the value
+// changes, c is synchronized as well.
+// Panic if v is not a pointer or if c is not mounted.
+func Consume(c Componer, v interface{}) bool {
+	pv := reflect.ValueOf(v)
+	if k := pv.Kind(); k != reflect.Ptr || pv.IsNil() {
+		log.Panic(errors.Errorf("consume accepts only non nil values of kind %v: %v", reflect.Ptr, k))
+	}
+
+	compo := instance(c)
+	t := pv.Type().Elem()
+
+	for provider := compo.Parent; provider != nil; provider = provider.Parent {
+		value, provided := providedValue(provider, t)
+		if !provided {
+			continue
+		}
+
+		if compo.Consumed == nil {
+			compo.Consumed = map[reflect.Type]consumption{}
+		}
+		if provider.Consumers == nil {
+			provider.Consumers = map[*component]bool{}
+		}
+
+		compo.Consumed[t] = consumption{
+			Provider: provider,
+			Value:    value,
+		}
+		provider.Consumers[compo] = true
+
+		pv.Elem().Set(reflect.ValueOf(value))
+		return true
+	}
+	return false
+}
+
+func providedValue(compo *component, t reflect.Type) (value interface{}, provided bool) {
+	provider, isProvider := compo.Component.(Provider)
+	if !isProvider {
+		return
+	}
+
+	for _, v := range provider.Provide() {
+		if v != nil && reflect.TypeOf(v) == t {
+			return v, true
+		}
+	}
+	return
+}
+
+// syncConsumers synchronizes the components which consumed a value provided
+// by compo that changed since they were rendered. Consumers are synchronized
+// from the top to the bottom of the tree.
+func syncConsumers(compo *component, txn *transaction) (syncs []Sync, err error) {
+	var outdated []*component
+
+	for consumer := range compo.Consumers {
+		for t, consumed := range consumer.Consumed {
+			if consumed.Provider != compo {
+				continue
+			}
+
+			if value, _ := providedValue(compo, t); !reflect.DeepEqual(value, consumed.Value) {
+				outdated = append(outdated, consumer)
+				break
+			}
+		}
+	}
+
+	sort.Sort(byDepth(outdated))
+
+	for _, consumer := range outdated {
+		// A consumer can be dismounted by the synchronization of another one.
+		if !compo.Consumers[consumer] {
+			continue
+		}
+
+		var consumerSyncs []Sync
+		if consumerSyncs, err = synchronizeInstance(consumer, txn); err != nil {
+			return nil, err
+		}
+		syncs = append(syncs, consumerSyncs...)
+	}
+	return
+}
+
+// releaseConsumer removes compo from the consumers of its providers.
+func releaseConsumer(compo *component) {
+	for _, consumed := range compo.Consumed {
+		delete(consumed.Provider.Consumers, compo)
+	}
+	compo.Consumed = nil
+}
+
+// restoreConsumer replaces the consumptions of compo by consumed.
+func restoreConsumer(compo *component, consumed map[reflect.Type]consumption) {
+	releaseConsumer(compo)
+
+	for _, c := range consumed {
+		if c.Provider.Consumers == nil {
+			c.Provider.Consumers = map[*component]bool{}
+		}
+		c.Provider.Consumers[compo] = true
+	}
+	compo.Consumed = consumed
+}
+
+// byDepth sorts components from the top to the bottom of the tree.
+type byDepth []*component
+
+func (s byDepth) Len() int           { return len(s) }
+func (s byDepth) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byDepth) Less(i, j int) bool { return depth(s[i]) < depth(s[j]) }
+
+func depth(compo *component) int {
+	d := 0
+	for ; compo.Parent != nil; compo = compo.Parent {
+		d++
+	}
+	return d
+}
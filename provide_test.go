@@ -0,0 +1,151 @@
+package markup
+
+import (
+	"testing"
+
+	"github.com/satori/go.uuid"
+)
+
+type Theme struct {
+	Color string
+}
+
+type CompoThemeProvider struct {
+	Color string
+}
+
+func (c *CompoThemeProvider) Provide() []interface{} {
+	return []interface{}{
+		Theme{Color: c.Color},
+	}
+}
+
+func (c *CompoThemeProvider) Render() string {
+	return `
+<div>
+    <CompoThemeMiddle />
+</div>
+    `
+}
+
+type CompoThemeMiddle struct {
+	Title string
+}
+
+func (c *CompoThemeMiddle) Render() string {
+	return `
+<div>
+    <CompoThemeConsumer />
+</div>
+    `
+}
+
+type CompoThemeConsumer struct {
+	provided bool
+}
+
+func (c *CompoThemeConsumer) Theme() Theme {
+	var t Theme
+	c.provided = Consume(c, &t)
+	return t
+}
+
+func (c *CompoThemeConsumer) Render() string {
+	return `<p class="{{.Theme.Color}}">Themed</p>`
+}
+
+func init() {
+	Register(&CompoThemeProvider{})
+	Register(&CompoThemeMiddle{})
+	Register(&CompoThemeConsumer{})
+}
+
+func TestConsume(t *testing.T) {
+	c := &CompoThemeProvider{Color: "red"}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	middle := root.Children[0].compo.Root
+	consumer := middle.Children[0].compo
+	consumerRoot := consumer.Root
+
+	if !consumer.Component.(*CompoThemeConsumer).provided {
+		t.Fatal("theme should have been provided")
+	}
+
+	if class := consumerRoot.Attributes["class"]; class != "red" {
+		t.Fatal("class should be red:", class)
+	}
+
+	c.Color = "blue"
+
+	syncs, err := Synchronize(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 1 {
+		t.Fatal("l should be 1:", l)
+	}
+
+	s := syncs[0]
+	if s.Scope != AttrSync || s.Node != consumerRoot {
+		t.Error("s should be an AttrSync of the consumer root")
+	}
+
+	if class := s.Attributes["class"]; class != "blue" {
+		t.Error("class should be blue:", class)
+	}
+
+	// Value unchanged.
+	if syncs, err = Synchronize(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 0 {
+		t.Error("l should be 0:", l)
+	}
+}
+
+func TestConsumeNotProvided(t *testing.T) {
+	c := &CompoThemeConsumer{}
+
+	if _, err := Mount(c, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	if c.provided {
+		t.Error("theme should not have been provided")
+	}
+}
+
+func TestConsumeDismountedConsumer(t *testing.T) {
+	c := &CompoThemeProvider{Color: "red"}
+
+	if _, err := Mount(c, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+
+	compo := instance(c)
+	if l := len(compo.Consumers); l != 1 {
+		t.Fatal("consumers len should be 1:", l)
+	}
+
+	Dismount(c)
+
+	if l := len(compo.Consumers); l != 0 {
+		t.Error("consumers len should be 0:", l)
+	}
+}
+
+func TestConsumeNotPointer(t *testing.T) {
+	defer func() { recover() }()
+
+	Consume(&CompoThemeConsumer{}, Theme{})
+	t.Error("should panic")
+}
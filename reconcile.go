@@ -0,0 +1,337 @@
+package markup
+
+import "github.com/satori/go.uuid"
+
+// keyAttr is the attribute which identifies an element or a component among
+// its siblings. Keyed children are reconciled by key rather than by position:
+// kept children are reused with their ids and their component instances.
+const keyAttr = "key"
+
+func hasKeys(children []*Node) bool {
+	for _, c := range children {
+		if _, keyed := c.Attributes[keyAttr]; keyed {
+			return true
+		}
+	}
+	return false
+}
+
+// sameKind reports whether a and b can be synchronized without replacing
+// one by the other.
+func sameKind(a *Node, b *Node) bool {
+	return a.Type == b.Type && a.Tag == b.Tag
+}
+
+// match matches new children with live children, by key when some of them
+// are keyed.
+func match(live []*Node, new []*Node) (matches []int, replaces []int) {
+	if hasKeys(live) || hasKeys(new) {
+		return matchKeyedChildren(live, new)
+	}
+	return matchChildren(live, new)
+}
+
+// matchChildren computes a minimal edit script between live and new children
+// from their longest common subsequence of nodes of the same kind.
+// It returns, for each new child, the index of the live child it is
+// synchronized with (matches) or the index of the live child it replaces
+// (replaces). Both are -1 when the new child is inserted.
+func matchChildren(live []*Node, new []*Node) (matches []int, replaces []int) {
+	matches = newIndexes(len(new))
+	replaces = newIndexes(len(new))
+
+	start := 0
+	for start < len(live) && start < len(new) && sameKind(live[start], new[start]) {
+		matches[start] = start
+		start++
+	}
+
+	liveEnd := len(live)
+	newEnd := len(new)
+	for liveEnd > start && newEnd > start && sameKind(live[liveEnd-1], new[newEnd-1]) {
+		liveEnd--
+		newEnd--
+		matches[newEnd] = liveEnd
+	}
+
+	m := liveEnd - start
+	n := newEnd - start
+	lcs := make([][]int, m+1)
+	for i := range lcs {
+		lcs[i] = make([]int, n+1)
+	}
+
+	for i := m - 1; i >= 0; i-- {
+		for j := n - 1; j >= 0; j-- {
+			switch {
+			case sameKind(live[start+i], new[start+j]):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// Live and new children between two matches are paired as replacements.
+	// The remaining ones are removed or inserted.
+	var liveGap, newGap []int
+	pairGaps := func() {
+		for k := 0; k < len(liveGap) && k < len(newGap); k++ {
+			replaces[newGap[k]] = liveGap[k]
+		}
+		liveGap = liveGap[:0]
+		newGap = newGap[:0]
+	}
+
+	i, j := 0, 0
+	for i < m && j < n {
+		switch {
+		case sameKind(live[start+i], new[start+j]):
+			pairGaps()
+			matches[start+j] = start + i
+			i++
+			j++
+
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			liveGap = append(liveGap, start+i)
+			i++
+
+		default:
+			newGap = append(newGap, start+j)
+			j++
+		}
+	}
+
+	for ; i < m; i++ {
+		liveGap = append(liveGap, start+i)
+	}
+	for ; j < n; j++ {
+		newGap = append(newGap, start+j)
+	}
+	pairGaps()
+	return
+}
+
+// matchKeyedChildren is like matchChildren but matches keyed children by key.
+// Children without key are paired in order with the live children without
+// key. Paired children of a different kind are replaced.
+func matchKeyedChildren(live []*Node, new []*Node) (matches []int, replaces []int) {
+	matches = newIndexes(len(new))
+	replaces = newIndexes(len(new))
+	keys := map[string]int{}
+	var unkeyed []int
+
+	for i, c := range live {
+		key, keyed := c.Attributes[keyAttr]
+		if !keyed {
+			unkeyed = append(unkeyed, i)
+			continue
+		}
+
+		if _, duplicated := keys[key]; !duplicated {
+			keys[key] = i
+		}
+	}
+
+	for i, c := range new {
+		j := -1
+
+		if key, keyed := c.Attributes[keyAttr]; keyed {
+			if k, found := keys[key]; found {
+				j = k
+				delete(keys, key)
+			}
+		} else if len(unkeyed) != 0 {
+			j = unkeyed[0]
+			unkeyed = unkeyed[1:]
+		}
+
+		switch {
+		case j == -1:
+
+		case sameKind(live[j], c):
+			matches[i] = j
+
+		default:
+			replaces[i] = j
+		}
+	}
+	return
+}
+
+func newIndexes(n int) []int {
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = -1
+	}
+	return indexes
+}
+
+// syncMatchedChildren synchronizes the matched children of live and new.
+func syncMatchedChildren(live *Node, new *Node, matches []int, compo *component, txn *transaction) (syncs []Sync, shouldFullSync bool, err error) {
+	for i, j := range matches {
+		if j == -1 {
+			continue
+		}
+
+		if c := live.Children[j]; c.Type == TextNode {
+			syncs = append(syncs, syncTextNodes(c, new.Children[i], i, txn)...)
+			continue
+		}
+
+		childSyncs, requireFullSync, err := syncNodes(live.Children[j], new.Children[i], compo, txn)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if requireFullSync {
+			shouldFullSync = true
+		}
+		syncs = append(syncs, childSyncs...)
+	}
+	return
+}
+
+// reconcileChildren updates the children of live to match the ones of new.
+// Matched live children are kept, new children which are inserted or which
+// replace a live child are mounted and the other live children are dismounted.
+// It returns the child syncs which transform the live children into the new
+// ones.
+func reconcileChildren(live *Node, new *Node, matches []int, replaces []int, compo *component, txn *transaction) (syncs []Sync, err error) {
+	for i, c := range new.Children {
+		if matches[i] != -1 {
+			continue
+		}
+
+		if err = mountNode(c, compo, txn); err != nil {
+			return nil, err
+		}
+	}
+
+	children := reconciledChildren(live.Children, new.Children, matches)
+	syncs = childSyncs(live.ID, live.Children, children, matches, replaces)
+
+	kept := make([]bool, len(live.Children))
+	for _, j := range matches {
+		if j != -1 {
+			kept[j] = true
+		}
+	}
+
+	for j, c := range live.Children {
+		if !kept[j] {
+			dismountNode(c, txn)
+		}
+	}
+
+	live.Children = children
+	for _, c := range children {
+		c.Parent = live
+	}
+	return
+}
+
+// reconciledChildren returns the children resulting from the reconciliation
+// of live with new: matched live children followed in place by the new
+// children which are inserted or which replace a live child.
+func reconciledChildren(live []*Node, new []*Node, matches []int) []*Node {
+	children := make([]*Node, len(new))
+
+	for i, c := range new {
+		if j := matches[i]; j != -1 {
+			children[i] = live[j]
+			continue
+		}
+		children[i] = c
+	}
+	return children
+}
+
+// childSyncs returns the child syncs which transform the live children of the
+// node designated by parentID into children.
+// Live children which are neither matched nor replaced are removed first, from
+// the last to the first. Replacements follow, then moves and insertions in
+// the order of children.
+func childSyncs(parentID uuid.UUID, live []*Node, children []*Node, matches []int, replaces []int) (syncs []Sync) {
+	kept := make([]bool, len(live))
+	for _, j := range matches {
+		if j != -1 {
+			kept[j] = true
+		}
+	}
+
+	replaced := make([]bool, len(live))
+	for _, j := range replaces {
+		if j != -1 {
+			replaced[j] = true
+		}
+	}
+
+	current := make([]*Node, len(live))
+	copy(current, live)
+
+	for j := len(live) - 1; j >= 0; j-- {
+		if kept[j] || replaced[j] {
+			continue
+		}
+
+		syncs = append(syncs, Sync{
+			Scope:    RemoveChild,
+			ParentID: parentID,
+			Index:    j,
+			Node:     live[j],
+		})
+		current = append(current[:j], current[j+1:]...)
+	}
+
+	for i, j := range replaces {
+		if j == -1 {
+			continue
+		}
+
+		index := indexOf(current, live[j])
+		syncs = append(syncs, Sync{
+			Scope:    ReplaceChild,
+			ParentID: parentID,
+			Index:    index,
+			Node:     children[i],
+		})
+		current[index] = children[i]
+	}
+
+	for i, c := range children {
+		if i < len(current) && current[i] == c {
+			continue
+		}
+
+		s := Sync{
+			Scope:    InsertChild,
+			ParentID: parentID,
+			Index:    i,
+			Node:     c,
+		}
+
+		if index := indexOf(current, c); index != -1 {
+			s.Scope = MoveChild
+			s.From = index
+			current = append(current[:index], current[index+1:]...)
+		}
+
+		syncs = append(syncs, s)
+		current = append(current[:i], append([]*Node{c}, current[i:]...)...)
+	}
+	return
+}
+
+func indexOf(nodes []*Node, n *Node) int {
+	for i, c := range nodes {
+		if c == n {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,189 @@
+package markup
+
+import (
+	"testing"
+
+	"github.com/satori/go.uuid"
+)
+
+type CompoList struct {
+	Tags []string
+	Keys bool
+}
+
+func (c *CompoList) Render() string {
+	return `
+<div>
+    {{$keys := .Keys}}
+    {{range .Tags}}
+        <{{.}} {{if $keys}}key="{{.}}"{{end}}></{{.}}>
+    {{end}}
+</div>
+    `
+}
+
+func init() {
+	Register(&CompoList{})
+}
+
+// applyChildSyncs applies child syncs to children like a driver would do.
+func applyChildSyncs(t *testing.T, children []*Node, syncs []Sync) []*Node {
+	for _, s := range syncs {
+		switch s.Scope {
+		case InsertChild:
+			children = append(children[:s.Index], append([]*Node{s.Node}, children[s.Index:]...)...)
+
+		case RemoveChild:
+			if children[s.Index].ID != s.Node.ID {
+				t.Fatalf("node to remove is not at index %v", s.Index)
+			}
+			children = append(children[:s.Index], children[s.Index+1:]...)
+
+		case MoveChild:
+			index := indexOf(children, s.Node)
+			if index == -1 {
+				t.Fatal("node to move is not a child")
+			}
+			children = append(children[:index], children[index+1:]...)
+			children = append(children[:s.Index], append([]*Node{s.Node}, children[s.Index:]...)...)
+
+		case ReplaceChild:
+			children[s.Index] = s.Node
+
+		default:
+			t.Fatalf("unexpected sync scope: %v", s.Scope)
+		}
+	}
+	return children
+}
+
+func TestSynchronizeChildren(t *testing.T) {
+	tests := []struct {
+		scenario string
+		live     []string
+		new      []string
+		keys     bool
+		syncs    int
+	}{
+		{
+			scenario: "append",
+			live:     []string{"h1", "p"},
+			new:      []string{"h1", "p", "p"},
+			syncs:    1,
+		},
+		{
+			scenario: "prepend",
+			live:     []string{"h1", "p"},
+			new:      []string{"span", "h1", "p"},
+			syncs:    1,
+		},
+		{
+			scenario: "remove middle",
+			live:     []string{"h1", "span", "p"},
+			new:      []string{"h1", "p"},
+			syncs:    1,
+		},
+		{
+			scenario: "replace",
+			live:     []string{"h1", "span", "p"},
+			new:      []string{"h1", "div", "p"},
+			syncs:    1,
+		},
+		{
+			scenario: "mixed",
+			live:     []string{"h1", "span", "p", "ul", "a"},
+			new:      []string{"h2", "p", "a", "ol", "b", "i"},
+		},
+		{
+			scenario: "keyed reverse",
+			live:     []string{"h1", "h2", "h3", "h4"},
+			new:      []string{"h4", "h3", "h2", "h1"},
+			keys:     true,
+		},
+		{
+			scenario: "keyed mixed",
+			live:     []string{"h1", "h2", "h3", "h4"},
+			new:      []string{"h3", "h5", "h1", "h6"},
+			keys:     true,
+		},
+	}
+
+	for _, test := range tests {
+		c := &CompoList{
+			Tags: test.live,
+			Keys: test.keys,
+		}
+
+		root, err := Mount(c, uuid.NewV1())
+		if err != nil {
+			t.Fatalf("%v: %v", test.scenario, err)
+		}
+
+		children := make([]*Node, len(root.Children))
+		copy(children, root.Children)
+
+		c.Tags = test.new
+
+		syncs, err := Synchronize(c)
+		if err != nil {
+			t.Fatalf("%v: %v", test.scenario, err)
+		}
+
+		if test.syncs != 0 && len(syncs) != test.syncs {
+			t.Errorf("%v: syncs len should be %v: %v", test.scenario, test.syncs, len(syncs))
+		}
+
+		for _, s := range syncs {
+			if s.ParentID != root.ID {
+				t.Errorf("%v: sync parent id should be %v: %v", test.scenario, root.ID, s.ParentID)
+			}
+		}
+
+		children = applyChildSyncs(t, children, syncs)
+
+		if len(children) != len(root.Children) {
+			t.Fatalf("%v: children len should be %v: %v", test.scenario, len(root.Children), len(children))
+		}
+
+		for i, n := range root.Children {
+			if children[i].ID != n.ID {
+				t.Errorf("%v: child %v should be %v: %v", test.scenario, i, n.Tag, children[i].Tag)
+			}
+
+			if n.Tag != test.new[i] {
+				t.Errorf("%v: live child %v should be %v: %v", test.scenario, i, test.new[i], n.Tag)
+			}
+		}
+
+		Dismount(c)
+	}
+}
+
+func TestMatchChildren(t *testing.T) {
+	live := []*Node{
+		{Tag: "h1"},
+		{Tag: "span"},
+		{Tag: "p"},
+	}
+	new := []*Node{
+		{Tag: "h1"},
+		{Tag: "div"},
+		{Tag: "p"},
+		{Tag: "p"},
+	}
+
+	matches, replaces := matchChildren(live, new)
+
+	expectedMatches := []int{0, -1, -1, 2}
+	expectedReplaces := []int{-1, 1, -1, -1}
+
+	for i := range new {
+		if matches[i] != expectedMatches[i] {
+			t.Errorf("matches[%v] should be %v: %v", i, expectedMatches[i], matches[i])
+		}
+
+		if replaces[i] != expectedReplaces[i] {
+			t.Errorf("replaces[%v] should be %v: %v", i, expectedReplaces[i], replaces[i])
+		}
+	}
+}
@@ -3,6 +3,8 @@ package markup
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"text/template"
 	"time"
 )
@@ -11,8 +13,9 @@ import (
 type TemplateFuncMapper interface {
 	// Allows to add custom functions to the template used to render the
 	// component.
-	// Note that funcs named json and time are already implemented to handle
-	// structs as prop and time format. Overloads of these will be ignored.
+	// Note that funcs named json, time and arg are already implemented to
+	// handle structs as prop, time format and static arguments of event calls.
+	// Overloads of these will be ignored.
 	// See https://golang.org/pkg/text/template/#Template.Funcs for more details.
 	FuncMaps() template.FuncMap
 }
@@ -29,6 +32,7 @@ func render(c Componer) (rendered string, err error) {
 	}
 	fnmap["json"] = convertToJSON
 	fnmap["time"] = formatTime
+	fnmap["arg"] = quoteArg
 
 	tmpl := template.Must(template.New("Render").Funcs(fnmap).Parse(c.Render()))
 	if err = tmpl.Execute(&b, c); err != nil {
@@ -47,3 +51,19 @@ func convertToJSON(v interface{}) string {
 func formatTime(t time.Time, layout string) string {
 	return t.Format(layout)
 }
+
+// quoteArg quotes v to be written as a static argument of an event call.
+// Times are formatted with RFC 3339, like bound values.
+func quoteArg(v interface{}) string {
+	var s string
+
+	if t, ok := v.(time.Time); ok {
+		s = t.Format(time.RFC3339)
+	} else {
+		s = fmt.Sprint(v)
+	}
+
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `'`, `\'`, -1)
+	return template.HTMLEscapeString("'" + s + "'")
+}
@@ -0,0 +1,231 @@
+package markup
+
+import "sort"
+
+var (
+	dirty = map[*component]bool{}
+)
+
+// AutoSync enables the synchronization of the components which handle
+// events. When it is true, HandleEvent invalidates the component of the node
+// which sent an event once the method called or the field mapped by the event
+// succeeded: handlers don't have to synchronize their component themselves.
+// The syncs are returned by the next call to Flush, so the events handled
+// within a frame produce a single batch.
+var AutoSync = false
+
+// Invalidate marks c as needing to be synchronized by the next call to Flush.
+// It does nothing if c is not mounted.
+func Invalidate(c Componer) {
+	for _, compo := range components[c] {
+		dirty[compo] = true
+	}
+}
+
+// Flush synchronizes the components marked with Invalidate and returns a
+// single batch of syncs.
+// Each component is synchronized once, parents before their children. Syncs
+// of nodes which are already covered by the sync of an ancestor, like a
+// FullSync, are dropped and AttrSyncs of a same node are merged.
+// Flush is intended to be called by drivers once per frame.
+// When a component fails to synchronize, the syncs produced so far are
+// returned with the error and the components which are not synchronized yet
+// remain marked.
+func Flush() (syncs []Sync, err error) {
+	compos := make([]*component, 0, len(dirty))
+	for compo := range dirty {
+		compos = append(compos, compo)
+	}
+	sort.Sort(byDepth(compos))
+
+	for _, compo := range compos {
+		// A component can be dismounted by the synchronization of its parent.
+		if !isMounted(compo) {
+			delete(dirty, compo)
+			continue
+		}
+
+		txn := &transaction{}
+
+		compoSyncs, serr := synchronizeInstance(compo, txn)
+		if serr != nil {
+			txn.rollback()
+			err = serr
+			break
+		}
+
+		txn.commit()
+		delete(dirty, compo)
+		syncs = append(syncs, compoSyncs...)
+	}
+
+	syncs = coalesceSyncs(syncs)
+	return
+}
+
+// autoSync invalidates c when AutoSync is enabled.
+func autoSync(c Componer) {
+	if AutoSync {
+		Invalidate(c)
+	}
+}
+
+func isMounted(compo *component) bool {
+	for _, mounted := range components[compo.Component] {
+		if mounted == compo {
+			return true
+		}
+	}
+	return false
+}
+
+// coalesceSyncs removes the syncs which are redundant once the whole batch is
+// applied: syncs of nodes which are not mounted anymore, syncs of nodes within
+// a subtree which is fully sent by another sync and duplicated syncs.
+// AttrSyncs of a same node are merged.
+func coalesceSyncs(syncs []Sync) []Sync {
+	type syncKey struct {
+		Scope SyncScope
+		Node  *Node
+	}
+
+	sent := map[*Node]bool{}
+
+	for _, s := range syncs {
+		switch s.Scope {
+		case FullSync, InsertChild, ReplaceChild:
+			sent[s.Node] = true
+		}
+	}
+
+	var coalesced []Sync
+	attrSyncs := map[*Node]int{}
+	seen := map[syncKey]bool{}
+
+	for _, s := range syncs {
+		n := s.Node
+
+		// A removed node is not mounted anymore: its parent is checked
+		// instead.
+		if s.Scope == RemoveChild {
+			if n = n.Parent; sent[n] {
+				continue
+			}
+		}
+
+		if !isAttached(n) || isCovered(n, sent) {
+			continue
+		}
+
+		switch s.Scope {
+		case AttrSync:
+			if sent[n] {
+				continue
+			}
+
+			if i, merged := attrSyncs[n]; merged {
+				coalesced[i] = mergeAttrSyncs(coalesced[i], s)
+				continue
+			}
+
+			s = mergeAttrSyncs(Sync{
+				Scope:      AttrSync,
+				Node:       n,
+				Attributes: AttributeMap{},
+			}, s)
+			attrSyncs[n] = len(coalesced)
+
+		case TextSync, FullSync:
+			key := syncKey{
+				Scope: s.Scope,
+				Node:  n,
+			}
+
+			if seen[key] || (s.Scope == TextSync && sent[n]) {
+				continue
+			}
+			seen[key] = true
+		}
+
+		coalesced = append(coalesced, s)
+	}
+	return coalesced
+}
+
+// mergeAttrSyncs applies the attribute and property changes of next on top of
+// the ones of s. The attributes and the properties of s must not be shared.
+func mergeAttrSyncs(s Sync, next Sync) Sync {
+	var removed []string
+	for _, name := range s.RemovedAttributes {
+		if _, set := next.Attributes[name]; !set {
+			removed = append(removed, name)
+		}
+	}
+
+	for name, value := range next.Attributes {
+		s.Attributes[name] = value
+	}
+
+	for _, name := range next.RemovedAttributes {
+		delete(s.Attributes, name)
+
+		if indexOfString(removed, name) == -1 {
+			removed = append(removed, name)
+		}
+	}
+
+	sort.Strings(removed)
+	s.RemovedAttributes = removed
+
+	for name, value := range next.Properties {
+		if s.Properties == nil {
+			s.Properties = AttributeMap{}
+		}
+		s.Properties[name] = value
+	}
+	return s
+}
+
+func indexOfString(strs []string, s string) int {
+	for i, str := range strs {
+		if str == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// isCovered reports whether an ancestor of n is in sent.
+func isCovered(n *Node, sent map[*Node]bool) bool {
+	for p := parentNode(n); p != nil; p = parentNode(p) {
+		if sent[p] {
+			return true
+		}
+	}
+	return false
+}
+
+// isAttached reports whether n is part of the tree of a mounted component.
+func isAttached(n *Node) bool {
+	for ; n != nil; n = parentNode(n) {
+		if n.Type == HTMLNode {
+			return nodes[n.ID] == n
+		}
+	}
+	return false
+}
+
+// parentNode returns the parent of n. The parent of a component root is the
+// node of the component in the tree of its parent component.
+func parentNode(n *Node) *Node {
+	if n.Parent != nil {
+		return n.Parent
+	}
+
+	for _, compo := range components[n.Mount] {
+		if compo.Root == n {
+			return compo.Node
+		}
+	}
+	return nil
+}
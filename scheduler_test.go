@@ -0,0 +1,300 @@
+package markup
+
+import (
+	"testing"
+
+	"github.com/satori/go.uuid"
+)
+
+type CompoFlush struct {
+	Class    string
+	Children []string
+}
+
+func (c *CompoFlush) Render() string {
+	return `
+<div class="{{.Class}}">
+    {{range .Children}}
+        <CompoFlushChild key="{{.}}" />
+    {{end}}
+</div>
+    `
+}
+
+type CompoFlushChild struct {
+	Class string
+}
+
+func (c *CompoFlushChild) Render() string {
+	return `<p class="{{.Class}}">Child</p>`
+}
+
+type CompoAutoSync struct {
+	Count int
+	Name  string
+}
+
+func (c *CompoAutoSync) Render() string {
+	return `
+<div>
+    <button onclick="Increment">{{.Count}}</button>
+    <input onchange="Name" class="{{.Name}}" />
+</div>
+    `
+}
+
+func (c *CompoAutoSync) Increment() {
+	c.Count++
+}
+
+func init() {
+	Register(&CompoFlush{})
+	Register(&CompoFlushChild{})
+	Register(&CompoAutoSync{})
+}
+
+func TestFlush(t *testing.T) {
+	c := &CompoFlush{Children: []string{"a", "b"}}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	a := root.Children[0].Component.(*CompoFlushChild)
+	b := root.Children[1].Component.(*CompoFlushChild)
+
+	// Invalidated several times, synchronized once.
+	c.Class = "parent"
+	Invalidate(c)
+	Invalidate(c)
+
+	a.Class = "a"
+	Invalidate(a)
+
+	// Dismounted by the synchronization of its parent.
+	b.Class = "b"
+	Invalidate(b)
+	c.Children = []string{"a"}
+
+	syncs, err := Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 3 {
+		t.Fatal("l should be 3:", l)
+	}
+
+	if s := syncs[0]; s.Scope != AttrSync || s.Node != root {
+		t.Error("first sync should be the parent AttrSync")
+	}
+
+	if s := syncs[1]; s.Scope != RemoveChild || s.Node.Component != b {
+		t.Error("second sync should remove b")
+	}
+
+	if s := syncs[2]; s.Scope != AttrSync || s.Node != Root(a) {
+		t.Error("third sync should be the AttrSync of a")
+	}
+
+	if l := len(dirty); l != 0 {
+		t.Error("dirty len should be 0:", l)
+	}
+
+	// Nothing to flush.
+	if syncs, err = Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 0 {
+		t.Error("l should be 0:", l)
+	}
+}
+
+func TestFlushError(t *testing.T) {
+	c := &CompoSyncError{}
+	if _, err := Mount(c, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	compo := instance(c)
+	c.BadTemplate = true
+	Invalidate(c)
+
+	if _, err := Flush(); err == nil {
+		t.Error("err should not be nil")
+	}
+
+	if !dirty[compo] {
+		t.Error("c should remain invalidated")
+	}
+
+	Dismount(c)
+
+	if dirty[compo] {
+		t.Error("dismounted c should not remain invalidated")
+	}
+}
+
+func TestInvalidateNotMounted(t *testing.T) {
+	Invalidate(&CompoFlush{})
+
+	if l := len(dirty); l != 0 {
+		t.Error("dirty len should be 0:", l)
+	}
+}
+
+func TestCoalesceSyncs(t *testing.T) {
+	c := &CompoFlush{Children: []string{"a"}}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	child := root.Children[0].compo.Root
+	text := child.Children[0]
+	detached := &Node{Type: HTMLNode, Tag: "p"}
+
+	syncs := []Sync{
+		{Scope: AttrSync, Node: root, Attributes: AttributeMap{"class": "a"}},
+		{Scope: TextSync, Node: text},
+		{Scope: FullSync, Node: child},
+		{Scope: AttrSync, Node: child, Attributes: AttributeMap{"class": "b"}},
+		{Scope: AttrSync, Node: root, Attributes: AttributeMap{"id": "c"}},
+		{Scope: FullSync, Node: child},
+		{Scope: RemoveChild, ParentID: child.ID, Node: text},
+		{Scope: AttrSync, Node: detached, Attributes: AttributeMap{"class": "d"}},
+	}
+
+	coalesced := coalesceSyncs(syncs)
+
+	if l := len(coalesced); l != 2 {
+		t.Fatal("l should be 2:", l)
+	}
+
+	if s := coalesced[0]; s.Scope != AttrSync || s.Node != root || len(s.Attributes) != 2 {
+		t.Error("attr syncs of root should be merged:", s.Attributes)
+	}
+
+	if s := coalesced[1]; s.Scope != FullSync || s.Node != child {
+		t.Error("second sync should be the FullSync of the child root")
+	}
+
+	// A FullSync covers the nodes of the child components.
+	coalesced = coalesceSyncs([]Sync{
+		{Scope: TextSync, Node: text},
+		{Scope: FullSync, Node: root},
+	})
+
+	if l := len(coalesced); l != 1 {
+		t.Fatal("l should be 1:", l)
+	}
+
+	if s := coalesced[0]; s.Scope != FullSync || s.Node != root {
+		t.Error("sync should be the FullSync of root")
+	}
+}
+
+func TestMergeAttrSyncs(t *testing.T) {
+	s := Sync{
+		Scope:             AttrSync,
+		Attributes:        AttributeMap{"class": "a", "title": "b"},
+		RemovedAttributes: []string{"hidden", "id"},
+	}
+
+	s = mergeAttrSyncs(s, Sync{
+		Scope:             AttrSync,
+		Attributes:        AttributeMap{"hidden": "true"},
+		RemovedAttributes: []string{"title"},
+	})
+
+	if l := len(s.Attributes); l != 2 || s.Attributes["class"] != "a" || s.Attributes["hidden"] != "true" {
+		t.Error("attributes should be class and hidden:", s.Attributes)
+	}
+
+	if len(s.RemovedAttributes) != 2 || s.RemovedAttributes[0] != "id" || s.RemovedAttributes[1] != "title" {
+		t.Error("removed attributes should be id and title:", s.RemovedAttributes)
+	}
+}
+
+func TestAutoSync(t *testing.T) {
+	c := &CompoAutoSync{}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	button := root.Children[0]
+	input := root.Children[1]
+
+	// Disabled.
+	if err = HandleEvent(button.ID, "onclick", "Increment", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	syncs, err := Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 0 {
+		t.Error("l should be 0:", l)
+	}
+
+	AutoSync = true
+	defer func() { AutoSync = false }()
+
+	// Several events, a single batch.
+	if err = HandleEvent(button.ID, "onclick", "Increment", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = HandleEvent(input.ID, "onchange", "Name", `{"value": "Maxence"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if syncs, err = Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 2 {
+		t.Fatal("l should be 2:", l)
+	}
+
+	if text := root.Children[0].Children[0].Text; text != "2" {
+		t.Error("text should be 2:", text)
+	}
+
+	if class := root.Children[1].Attributes["class"]; class != "Maxence" {
+		t.Error("class should be Maxence:", class)
+	}
+
+	// Handle and flush at once.
+	if syncs, err = HandleEventSync(button.ID, "onclick", "Increment", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 1 {
+		t.Fatal("l should be 1:", l)
+	}
+
+	if text := root.Children[0].Children[0].Text; text != "3" {
+		t.Error("text should be 3:", text)
+	}
+
+	// Failed handlers do not invalidate their component.
+	if _, err = HandleEventSync(input.ID, "onchange", "Name", `{"value": 42}`); err == nil {
+		t.Error("err should not be nil")
+	}
+
+	if l := len(dirty); l != 0 {
+		t.Error("l should be 0:", l)
+	}
+}
@@ -1,6 +1,6 @@
 package markup
 
-import "github.com/pkg/errors"
+import "github.com/satori/go.uuid"
 
 const (
 	// FullSync indicates that sync should replace the full node.
@@ -9,15 +9,54 @@ const (
 	// AttrSync indicates that sync should replace only the attributes of the
 	// node.
 	AttrSync
+
+	// InsertChild indicates that sync should insert the node as the child of
+	// the node designated by ParentID, at Index.
+	InsertChild
+
+	// RemoveChild indicates that sync should remove the node, which is
+	// currently at Index among the children of the node designated by
+	// ParentID.
+	RemoveChild
+
+	// MoveChild indicates that sync should move the node, which is the child
+	// at From of the node designated by ParentID, at Index.
+	MoveChild
+
+	// ReplaceChild indicates that sync should replace the child at Index of
+	// the node designated by ParentID by the node.
+	ReplaceChild
+
+	// TextSync indicates that sync should replace only the text of the node,
+	// which is the child at Index of the node designated by ParentID.
+	TextSync
 )
 
 // Sync is a struct which defines how a driver should handle a synchronisation
 // of a node on the native side.
+// Child syncs (InsertChild, RemoveChild, MoveChild, ReplaceChild and
+// TextSync) must be applied in order: Index refers to the children of the
+// parent as they are once the previous syncs have been applied. Indexes count
+// the children of Node: element, component and text nodes. Whitespace
+// between elements in the output of Markup is not a child.
+// An AttrSync sets Attributes and removes the attributes named in
+// RemovedAttributes. An attribute set to an empty string is not removed. It
+// also sets the native properties of the element in Properties.
+// Path is set by Diff, whose trees are usually not mounted and have no ids.
+// It designates by child indexes, from the root of the tree, the node
+// designated by ParentID for child syncs and the node of the other syncs. An
+// empty path designates the root. Like Index, it refers to the tree as it is
+// once the previous syncs have been applied.
 type Sync struct {
-	Scope      SyncScope
-	Index      int
-	Node       *Node
-	Attributes AttributeMap
+	Scope             SyncScope
+	ParentID          uuid.UUID
+	Path              []int
+	Index             int
+	From              int
+	Node              *Node
+	Attributes        AttributeMap
+	RemovedAttributes []string
+	Properties        AttributeMap
 }
 
 // SyncScope defines the scope of a sync.
@@ -25,85 +64,169 @@ type SyncScope uint8
 
 // Synchronize synchronize a whole component.
 // Compares the newer state with the live state of the component.
+// When c fails to render and is a subcomponent, the error is handled by the
+// nearest error boundary.
+// When c is a zero-size component mounted several times, all its instances are
+// synchronized.
+// Synchronize is transactional: when an error is returned, the live trees and
+// the mounted components are left as they were before the call, which keeps
+// them consistent with what the driver shows.
 func Synchronize(c Componer) (syncs []Sync, err error) {
-	live := Root(c)
+	instance(c)
 
-	r, err := render(c)
-	if err != nil {
-		err = errors.Errorf("unable to render %T: %v\n%v", c, err, c.Render())
+	compos := make([]*component, len(components[c]))
+	copy(compos, components[c])
+	txn := &transaction{}
+
+	for _, compo := range compos {
+		var compoSyncs []Sync
+
+		if compoSyncs, err = synchronizeInstance(compo, txn); err != nil {
+			txn.rollback()
+			return nil, err
+		}
+		syncs = append(syncs, compoSyncs...)
+	}
+
+	txn.commit()
+	return
+}
+
+func synchronizeInstance(compo *component, txn *transaction) (syncs []Sync, err error) {
+	savepoint := txn.savepoint()
+
+	if syncs, err = synchronize(compo, txn); err == nil || compo.Node == nil {
+		return
+	}
+
+	// The partial synchronization is undone before the component is replaced
+	// by a fallback.
+	txn.rollbackTo(savepoint)
+
+	host := compo.Node
+	if err = recoverSyncError(compo, err, txn); err != nil {
+		syncs = nil
+		return
+	}
+
+	s := Sync{
+		Scope: FullSync,
+		Node:  host.Parent,
+	}
+	syncs = []Sync{s}
+	return
+}
+
+func synchronize(compo *component, txn *transaction) (syncs []Sync, err error) {
+	f, memoized := fingerprint(compo.Component)
+	if upToDate(compo, f, memoized) {
 		return
 	}
 
-	new, err := stringToNode(r)
+	// Consumptions are recorded again while rendering.
+	consumed := compo.Consumed
+	previous := compo.Fingerprint
+	releaseConsumer(compo)
+
+	txn.onRollback(func() {
+		restoreConsumer(compo, consumed)
+		compo.Fingerprint = previous
+	})
+
+	new, err := renderNode(compo.Component)
 	if err != nil {
-		err = errors.Errorf("%T markup returned by Render() has a %v\n%v", c, err, r)
 		return
 	}
+	compo.Fingerprint = f
 
-	if new.Type != HTMLNode {
-		err = errors.Errorf("%T markup returned by Render() has a syntax error: root node is not a HTMLNode\n%v", c, r)
+	if syncs, _, err = syncNodes(compo.Root, new, compo, txn); err != nil {
 		return
 	}
 
-	syncs, _, err = syncNodes(live, new)
+	consumerSyncs, err := syncConsumers(compo, txn)
+	if err != nil {
+		return nil, err
+	}
+
+	syncs = append(syncs, consumerSyncs...)
 	return
 }
 
-func syncNodes(live *Node, new *Node) (syncs []Sync, parentShouldFullSync bool, err error) {
-	if live.Type != new.Type {
-		replaceNode(live, new)
-		parentShouldFullSync = true
-		return
+// recoverSyncError dismounts the subcomponent compo which failed to
+// synchronize and replaces it by the fallback of the nearest error boundary.
+// err is returned when there is no boundary.
+func recoverSyncError(compo *component, err error, txn *transaction) error {
+	boundary := findBoundary(compo.Parent)
+	if boundary == nil {
+		return err
 	}
 
-	switch live.Type {
-	case TextNode:
-		parentShouldFullSync = syncTextNodes(live, new)
+	dismount(compo, txn)
+	return replaceByFallback(compo.Node, compo.Parent, boundary, err, txn)
+}
 
+// syncNodes synchronizes live with new. live and new must be element or
+// component nodes of the same kind.
+func syncNodes(live *Node, new *Node, compo *component, txn *transaction) (syncs []Sync, parentShouldFullSync bool, err error) {
+	switch live.Type {
 	case ComponentNode:
-		syncs, parentShouldFullSync, err = syncComponentNodes(live, new)
+		syncs, parentShouldFullSync, err = syncComponentNodes(live, new, txn)
 
 	case HTMLNode:
-		syncs, parentShouldFullSync, err = syncHTMLNodes(live, new)
+		syncs, parentShouldFullSync, err = syncHTMLNodes(live, new, compo, txn)
 	}
 	return
 }
 
-func syncTextNodes(live *Node, new *Node) (changed bool) {
+// syncTextNodes synchronizes the text node live, which will be the child at
+// index of its parent, with new.
+func syncTextNodes(live *Node, new *Node, index int, txn *transaction) (syncs []Sync) {
 	if live.Text == new.Text {
 		return
 	}
 
+	txn.saveNode(live)
 	live.Text = new.Text
-	changed = true
+	s := Sync{
+		Scope:    TextSync,
+		ParentID: live.Parent.ID,
+		Index:    index,
+		Node:     live,
+	}
+	syncs = []Sync{s}
 	return
 }
 
-func syncComponentNodes(live *Node, new *Node) (syncs []Sync, parentShouldFullSync bool, err error) {
-	if live.Tag != new.Tag {
-		if err = replaceNode(live, new); err != nil {
-			return
-		}
-
-		parentShouldFullSync = true
-		return
-	}
-
-	attrDiff := live.Attributes.diff(new.Attributes)
-
-	if len(attrDiff) == 0 {
+func syncComponentNodes(live *Node, new *Node, txn *transaction) (syncs []Sync, parentShouldFullSync bool, err error) {
+	if set, removed := live.Attributes.diff(new.Attributes); len(set) == 0 && len(removed) == 0 {
 		return
 	}
 
+	txn.saveNode(live)
+	txn.saveComponent(live.Component)
 	live.Attributes = new.Attributes
+	live.Events = new.Events
 	decodeAttributeMap(new.Attributes, live.Component)
-	syncs, err = Synchronize(live.Component)
+
+	child := live.compo
+	savepoint := txn.savepoint()
+
+	if syncs, err = synchronize(child, txn); err != nil {
+		syncs = nil
+		txn.rollbackTo(savepoint)
+
+		if err = recoverSyncError(child, err, txn); err == nil {
+			parentShouldFullSync = true
+		}
+	}
 	return
 }
 
-func syncHTMLNodes(live *Node, new *Node) (syncs []Sync, parentShouldFullSync bool, err error) {
-	if live.Tag != new.Tag || len(live.Children) != len(new.Children) {
-		if err = mergeHTMLNodes(live, new); err != nil {
+func syncHTMLNodes(live *Node, new *Node, compo *component, txn *transaction) (syncs []Sync, parentShouldFullSync bool, err error) {
+	txn.saveNode(live)
+
+	if live.Tag != new.Tag {
+		if err = mergeHTMLNodes(live, new, compo, txn); err != nil {
 			return
 		}
 
@@ -115,26 +238,24 @@ func syncHTMLNodes(live *Node, new *Node) (syncs []Sync, parentShouldFullSync bo
 		return
 	}
 
-	shouldFullSync := false
-
-	for i := 0; i < len(live.Children); i++ {
-		childSyncs, requireFullSync, err := syncNodes(live.Children[i], new.Children[i])
-		if err != nil {
-			return nil, false, err
-		}
-
-		if requireFullSync && !shouldFullSync {
-			shouldFullSync = true
-		}
+	matches, replaces := match(live.Children, new.Children)
 
-		if shouldFullSync {
-			continue
-		}
+	childSyncs, shouldFullSync, err := syncMatchedChildren(live, new, matches, compo, txn)
+	if err != nil {
+		return
+	}
 
-		syncs = append(syncs, childSyncs...)
+	if syncs, err = reconcileChildren(live, new, matches, replaces, compo, txn); err != nil {
+		return nil, false, err
 	}
 
+	// Event calls are handled on the Go side: their arguments are updated
+	// without syncs.
+	live.Events = new.Events
+
 	if shouldFullSync {
+		live.Attributes = new.Attributes
+		setProperties(live, new.Properties)
 		s := Sync{
 			Scope: FullSync,
 			Node:  live,
@@ -143,51 +264,47 @@ func syncHTMLNodes(live *Node, new *Node) (syncs []Sync, parentShouldFullSync bo
 		return
 	}
 
-	if attrDiff := live.Attributes.diff(new.Attributes); len(attrDiff) != 0 {
+	syncs = append(syncs, childSyncs...)
+
+	set, removed := live.Attributes.diff(new.Attributes)
+	props := syncProperties(live, new)
+
+	if len(set) != 0 || len(removed) != 0 || len(props) != 0 {
 		live.Attributes = new.Attributes
 		s := Sync{
-			Scope:      AttrSync,
-			Node:       live,
-			Attributes: attrDiff,
+			Scope:             AttrSync,
+			Node:              live,
+			Attributes:        set,
+			RemovedAttributes: removed,
+			Properties:        props,
 		}
 		syncs = append([]Sync{s}, syncs...)
 	}
 	return
 }
 
-func replaceNode(live *Node, new *Node) error {
-	if live.Type == ComponentNode {
-		Dismount(live.Component)
+// mergeHTMLNodes replaces the tag, the attributes and the children of live by
+// the ones of new. Live children are dismounted only when all the new ones
+// have been successfully mounted.
+func mergeHTMLNodes(live *Node, new *Node, compo *component, txn *transaction) error {
+	for _, c := range new.Children {
+		if err := mountNode(c, compo, txn); err != nil {
+			return err
+		}
 	}
 
-	live.Tag = new.Tag
-	live.Type = new.Type
-	live.Text = new.Text
-	live.Attributes = new.Attributes
-	live.Children = new.Children
-
 	for _, c := range live.Children {
-		c.Parent = live
+		dismountNode(c, txn)
 	}
-	return mountNode(live, live.Mount, live.ContextID)
-}
 
-func mergeHTMLNodes(live *Node, new *Node) error {
 	live.Tag = new.Tag
 	live.Attributes = new.Attributes
-
-	for _, c := range live.Children {
-		dismountNode(c)
-	}
-
+	live.Events = new.Events
+	setProperties(live, new.Properties)
 	live.Children = new.Children
 
 	for _, c := range live.Children {
 		c.Parent = live
-
-		if err := mountNode(c, live.Mount, live.ContextID); err != nil {
-			return err
-		}
 	}
 	return nil
 }
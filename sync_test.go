@@ -1,6 +1,7 @@
 package markup
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/satori/go.uuid"
@@ -130,6 +131,8 @@ func init() {
 	Register(&SubCompoSync{})
 	Register(&SubCompoSyncBis{})
 	Register(&CompoSyncError{})
+	Register(&CompoSyncBool{})
+	Register(&CompoSyncTxn{})
 }
 
 func TestSynchronizeTextChange(t *testing.T) {
@@ -151,10 +154,18 @@ func TestSynchronizeTextChange(t *testing.T) {
 	}
 
 	s := syncs[0]
-	t.Log(s.Node.Markup())
+	t.Log(s.Node.Text)
+
+	if s.Scope != TextSync {
+		t.Error("s.Scope should be TextSync")
+	}
 
-	if s.Scope != FullSync {
-		t.Error("s.Scope should be FullSync")
+	if s.Node.Text != "Maxoo" {
+		t.Error("s.Node.Text should be Maxoo:", s.Node.Text)
+	}
+
+	if s.ParentID != s.Node.Parent.ID || s.Index != 0 {
+		t.Error("s should designate the first child of the text parent")
 	}
 }
 
@@ -188,6 +199,59 @@ func TestSynchronizeHTMLAttrChange(t *testing.T) {
 	}
 }
 
+type CompoSyncBool struct {
+	Checked bool
+}
+
+func (c *CompoSyncBool) Render() string {
+	return `<input type="checkbox" checked="{{.Checked}}" />`
+}
+
+func TestSynchronizeBooleanAttrChange(t *testing.T) {
+	c := &CompoSyncBool{Checked: true}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	c.Checked = false
+
+	syncs, err := Synchronize(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 1 {
+		t.Fatal("l should be 1:", l)
+	}
+
+	s := syncs[0]
+
+	if s.Scope != AttrSync || s.Node != root {
+		t.Error("s should be an AttrSync of root")
+	}
+
+	if l := len(s.Attributes); l != 0 {
+		t.Error("l should be 0:", l)
+	}
+
+	if len(s.RemovedAttributes) != 1 || s.RemovedAttributes[0] != "checked" {
+		t.Error("checked should be removed:", s.RemovedAttributes)
+	}
+
+	c.Checked = true
+
+	if syncs, err = Synchronize(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if s = syncs[0]; s.Attributes["checked"] != "true" || len(s.RemovedAttributes) != 0 {
+		t.Error("checked should be set:", s.Attributes, s.RemovedAttributes)
+	}
+}
+
 func TestSynchronizeHTMLTagChange(t *testing.T) {
 	c := &CompoSync{}
 	ctx := uuid.NewV1()
@@ -209,8 +273,12 @@ func TestSynchronizeHTMLTagChange(t *testing.T) {
 	s := syncs[0]
 	t.Log(s.Node.Markup())
 
-	if s.Scope != FullSync {
-		t.Error("s.Scope should be FullSync")
+	if s.Scope != ReplaceChild {
+		t.Error("s.Scope should be ReplaceChild")
+	}
+
+	if s.ParentID != ID(c) {
+		t.Errorf("s.ParentID should be %v: %v", ID(c), s.ParentID)
 	}
 }
 
@@ -222,10 +290,90 @@ func TestSynchronizeHTMLTagChangeError(t *testing.T) {
 	defer Dismount(c)
 
 	c.HTMLTagChangeError = true
+	componentsLen := len(components)
+	nodesLen := len(nodes)
 
 	if _, err := Synchronize(c); err == nil {
 		t.Error("error should not be nil")
 	}
+
+	if l := len(components); l != componentsLen {
+		t.Errorf("components len should be %v: %v", componentsLen, l)
+	}
+
+	if l := len(nodes); l != nodesLen {
+		t.Errorf("nodes len should be %v: %v", nodesLen, l)
+	}
+}
+
+type CompoSyncTxn struct {
+	Broken bool
+}
+
+func (c *CompoSyncTxn) Render() string {
+	return `
+<div class="{{if .Broken}}broken{{end}}">
+    <p>{{if .Broken}}Broken{{else}}Fine{{end}}</p>
+    <div>{{if not .Broken}}<CompoHooks />{{end}}</div>
+    <SubCompoSync Name="{{if .Broken}}Max{{else}}Maxence{{end}}" />
+    <div>{{if .Broken}}<CompoSyncError BadTemplate="true" />{{end}}</div>
+</div>
+    `
+}
+
+func TestSynchronizeRollback(t *testing.T) {
+	c := &CompoSyncTxn{}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	markup := Markup(c)
+	sub := root.Children[2].Component.(*SubCompoSync)
+	mounted := hooksMounted
+	componentsLen := len(components)
+	nodesLen := len(nodes)
+
+	c.Broken = true
+
+	if _, err = Synchronize(c); err == nil {
+		t.Fatal("err should not be nil")
+	}
+	t.Log(err)
+
+	if m := Markup(c); m != markup {
+		t.Errorf("markup should be unchanged:\n%v\n%v", markup, m)
+	}
+
+	if sub.Name != "Maxence" {
+		t.Error("sub.Name should be Maxence:", sub.Name)
+	}
+
+	if hooksMounted != mounted {
+		t.Errorf("OnDismount should not be called: %v", hooksMounted)
+	}
+
+	if l := len(components); l != componentsLen {
+		t.Errorf("components len should be %v: %v", componentsLen, l)
+	}
+
+	if l := len(nodes); l != nodesLen {
+		t.Errorf("nodes len should be %v: %v", nodesLen, l)
+	}
+
+	// The live tree is still usable.
+	c.Broken = false
+
+	syncs, err := Synchronize(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 0 {
+		t.Error("l should be 0:", l)
+	}
 }
 
 func TestSynchronizeCompoAttrChange(t *testing.T) {
@@ -249,8 +397,8 @@ func TestSynchronizeCompoAttrChange(t *testing.T) {
 	s := syncs[0]
 	t.Log(s.Node.Markup())
 
-	if s.Scope != FullSync {
-		t.Error("s.Scope should be FullSync")
+	if s.Scope != TextSync {
+		t.Error("s.Scope should be TextSync")
 	}
 }
 
@@ -275,8 +423,8 @@ func TestSynchronizeCompoChange(t *testing.T) {
 	s := syncs[0]
 	t.Log(s.Node.Markup())
 
-	if s.Scope != FullSync {
-		t.Error("s.Scope should be FullSync")
+	if s.Scope != ReplaceChild {
+		t.Error("s.Scope should be ReplaceChild")
 	}
 }
 
@@ -288,10 +436,20 @@ func TestSynchronizeCompoChangeError(t *testing.T) {
 	defer Dismount(c)
 
 	c.CompoChangeError = true
+	componentsLen := len(components)
+	nodesLen := len(nodes)
 
 	if _, err := Synchronize(c); err == nil {
 		t.Error("err should not be nil")
 	}
+
+	if l := len(components); l != componentsLen {
+		t.Errorf("components len should be %v: %v", componentsLen, l)
+	}
+
+	if l := len(nodes); l != nodesLen {
+		t.Errorf("nodes len should be %v: %v", nodesLen, l)
+	}
 }
 
 func TestSynchronizeTypeChange(t *testing.T) {
@@ -315,8 +473,8 @@ func TestSynchronizeTypeChange(t *testing.T) {
 	s := syncs[0]
 	t.Log(s.Node.Markup())
 
-	if s.Scope != FullSync {
-		t.Error("s.Scope should be FullSync")
+	if s.Scope != ReplaceChild {
+		t.Error("s.Scope should be ReplaceChild")
 	}
 }
 
@@ -342,8 +500,12 @@ func TestAddRemove(t *testing.T) {
 	s := syncs[0]
 	t.Log(s.Node.Markup())
 
-	if s.Scope != FullSync {
-		t.Error("s.Scope should be FullSync")
+	if s.Scope != InsertChild {
+		t.Error("s.Scope should be InsertChild")
+	}
+
+	if s.Index != 0 {
+		t.Error("s.Index should be 0:", s.Index)
 	}
 
 	// Remove.
@@ -361,8 +523,12 @@ func TestAddRemove(t *testing.T) {
 	s = syncs[0]
 	t.Log(s.Node.Markup())
 
-	if s.Scope != FullSync {
-		t.Error("s.Scope should be FullSync")
+	if s.Scope != RemoveChild {
+		t.Error("s.Scope should be RemoveChild")
+	}
+
+	if s.Index != 0 {
+		t.Error("s.Index should be 0:", s.Index)
 	}
 }
 
@@ -398,3 +564,229 @@ func TestSynchronizeBadMarkup(t *testing.T) {
 	c.BadMarkup = true
 	Synchronize(c)
 }
+
+func TestSynchronizeErrorBoundary(t *testing.T) {
+	c := &CompoBoundary{Fallback: `<p class="fallback">Oops</p>`}
+
+	if _, err := Mount(c, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	c.BreaksChild = true
+
+	syncs, err := Synchronize(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.err == nil {
+		t.Error("OnChildError should have been called")
+	}
+
+	if l := len(syncs); l != 1 {
+		t.Fatal("l should be 1:", l)
+	}
+
+	if s := syncs[0]; s.Scope != FullSync || s.Node != Root(c) {
+		t.Error("s should be a FullSync of the boundary root")
+	}
+
+	if m := Markup(c); !strings.Contains(m, "fallback") {
+		t.Error("markup should contain the fallback:", m)
+	}
+
+	// Recovers when the child can be rendered again.
+	c.BreaksChild = false
+
+	if _, err = Synchronize(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if m := Markup(c); strings.Contains(m, "fallback") {
+		t.Error("markup should not contain the fallback:", m)
+	}
+}
+
+func TestSynchronizeSubcomponentErrorBoundary(t *testing.T) {
+	c := &CompoBoundary{Fallback: `<p class="fallback">Oops</p>`}
+
+	if _, err := Mount(c, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	var child Componer
+	for _, n := range Root(c).Children {
+		if n.Tag == "CompoSyncError" {
+			child = n.Component
+		}
+	}
+
+	child.(*CompoSyncError).BadTemplate = true
+
+	syncs, err := Synchronize(child)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 1 {
+		t.Fatal("l should be 1:", l)
+	}
+
+	if s := syncs[0]; s.Scope != FullSync || s.Node != Root(c) {
+		t.Error("s should be a FullSync of the boundary root")
+	}
+}
+
+type CompoKeyed struct {
+	Items []string
+}
+
+func (c *CompoKeyed) Render() string {
+	return `
+<div>
+    <ul>
+        {{range .Items}}
+            <CompoKeyedRow key="{{.}}" Name="{{.}}" />
+        {{end}}
+    </ul>
+    <ol>
+        {{range .Items}}
+            <li key="{{.}}">{{.}}</li>
+        {{end}}
+    </ol>
+</div>
+    `
+}
+
+type CompoKeyedRow struct {
+	Name string
+}
+
+func (c *CompoKeyedRow) Render() string {
+	return `<p>{{.Name}}</p>`
+}
+
+func init() {
+	Register(&CompoKeyed{})
+	Register(&CompoKeyedRow{})
+}
+
+func keyedRows(c *CompoKeyed) map[string]*component {
+	rows := map[string]*component{}
+	for _, n := range Root(c).Children[0].Children {
+		rows[n.Attributes["key"]] = n.compo
+	}
+	return rows
+}
+
+func keyedItemIDs(c *CompoKeyed) map[string]uuid.UUID {
+	ids := map[string]uuid.UUID{}
+	for _, n := range Root(c).Children[1].Children {
+		ids[n.Attributes["key"]] = n.ID
+	}
+	return ids
+}
+
+func TestSynchronizeKeyed(t *testing.T) {
+	c := &CompoKeyed{Items: []string{"a", "b", "c"}}
+
+	if _, err := Mount(c, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	rows := keyedRows(c)
+	ids := keyedItemIDs(c)
+	nodesLen := len(nodes)
+
+	tests := []struct {
+		scenario string
+		items    []string
+		scope    SyncScope
+		index    int
+	}{
+		{
+			scenario: "insert",
+			items:    []string{"a", "x", "b", "c"},
+			scope:    InsertChild,
+			index:    1,
+		},
+		{
+			scenario: "remove",
+			items:    []string{"a", "x", "c"},
+			scope:    RemoveChild,
+			index:    2,
+		},
+		{
+			scenario: "move",
+			items:    []string{"c", "a", "x"},
+			scope:    MoveChild,
+			index:    0,
+		},
+	}
+
+	for _, test := range tests {
+		c.Items = test.items
+
+		syncs, err := Synchronize(c)
+		if err != nil {
+			t.Fatalf("%v: %v", test.scenario, err)
+		}
+
+		if l := len(syncs); l != 2 {
+			t.Fatalf("%v: l should be 2: %v", test.scenario, l)
+		}
+
+		for _, s := range syncs {
+			if s.Scope != test.scope || s.Index != test.index {
+				t.Errorf("%v: sync should have scope %v and index %v: %v %v",
+					test.scenario,
+					test.scope,
+					test.index,
+					s.Scope,
+					s.Index)
+			}
+		}
+
+		newRows := keyedRows(c)
+		newIDs := keyedItemIDs(c)
+
+		for _, key := range test.items {
+			if rows[key] != nil && newRows[key] != rows[key] {
+				t.Errorf("%v: row %v should have been reused", test.scenario, key)
+			}
+
+			if ids[key] != uuid.Nil && newIDs[key] != ids[key] {
+				t.Errorf("%v: item %v should have kept its id", test.scenario, key)
+			}
+		}
+
+		rows = newRows
+		ids = newIDs
+	}
+
+	// As many items as when mounted: removed ones have been dismounted.
+	if l := len(nodes); l != nodesLen {
+		t.Errorf("nodes len should be %v: %v", nodesLen, l)
+	}
+}
+
+func TestSynchronizeKeyedUnchanged(t *testing.T) {
+	c := &CompoKeyed{Items: []string{"a", "b"}}
+
+	if _, err := Mount(c, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	syncs, err := Synchronize(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(syncs); l != 0 {
+		t.Error("l should be 0:", l)
+	}
+}
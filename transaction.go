@@ -0,0 +1,99 @@
+package markup
+
+import "reflect"
+
+// transaction records how to undo the changes made to the registries and to
+// the live trees while mounting or synchronizing components, so a failing
+// operation leaves them as they were before it started.
+// Changes which cannot be undone, like calling OnDismount, are deferred until
+// the transaction is committed.
+type transaction struct {
+	entries []txnEntry
+}
+
+type txnEntry struct {
+	rollback func()
+	commit   func()
+}
+
+// onRollback registers f to be called when the transaction is rolled back.
+func (t *transaction) onRollback(f func()) {
+	t.entries = append(t.entries, txnEntry{rollback: f})
+}
+
+// onCommit registers f to be called when the transaction is committed.
+func (t *transaction) onCommit(f func()) {
+	t.entries = append(t.entries, txnEntry{commit: f})
+}
+
+// savepoint returns a mark that can be passed to rollbackTo or collapse.
+func (t *transaction) savepoint() int {
+	return len(t.entries)
+}
+
+// rollbackTo undoes, in reverse order, the changes recorded since savepoint.
+// The deferred changes recorded since savepoint are dropped.
+func (t *transaction) rollbackTo(savepoint int) {
+	for i := len(t.entries) - 1; i >= savepoint; i-- {
+		if rollback := t.entries[i].rollback; rollback != nil {
+			rollback()
+		}
+	}
+	t.entries = t.entries[:savepoint]
+}
+
+// rollback undoes all the changes recorded by the transaction.
+func (t *transaction) rollback() {
+	t.rollbackTo(0)
+}
+
+// commit applies, in order, the deferred changes recorded by the transaction.
+func (t *transaction) commit() {
+	entries := t.entries
+	t.entries = nil
+
+	for _, e := range entries {
+		if e.commit != nil {
+			e.commit()
+		}
+	}
+}
+
+// collapse replaces the changes recorded since savepoint by f. It is used
+// when a set of changes can be undone at once, like a mounted component that
+// is undone by dismounting it. Deferred changes are kept.
+func (t *transaction) collapse(savepoint int, f func()) {
+	entries := t.entries[:savepoint]
+
+	for _, e := range t.entries[savepoint:] {
+		if e.commit != nil {
+			entries = append(entries, e)
+		}
+	}
+	t.entries = append(entries, txnEntry{rollback: f})
+}
+
+// saveComponent records the state of c, which is restored when the
+// transaction is rolled back.
+func (t *transaction) saveComponent(c Componer) {
+	v := reflect.ValueOf(c).Elem()
+	saved := reflect.New(v.Type()).Elem()
+	saved.Set(v)
+
+	t.onRollback(func() { v.Set(saved) })
+}
+
+// saveNode records the state of n, which is restored when the transaction is
+// rolled back.
+func (t *transaction) saveNode(n *Node) {
+	saved := *n
+
+	if n.nativeProperties != nil {
+		saved.nativeProperties = make(AttributeMap, len(n.nativeProperties))
+		for name, value := range n.nativeProperties {
+			saved.nativeProperties[name] = value
+		}
+	}
+
+	t.onRollback(func() { *n = saved })
+}
@@ -0,0 +1,628 @@
+package markup
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+)
+
+// WireVersion is the version of the wire format used to send syncs to out of
+// process drivers.
+//
+// JSON encoding
+//
+// A batch of syncs is encoded as:
+//  {
+//    "version": 4,
+//    "syncs": [<sync>, ...]
+//  }
+//
+// A sync is encoded as:
+//  {
+//    "scope": "full" | "attr" | "insert" | "remove" | "move" | "replace" | "text",
+//    "parent": "<parent id>",      // Child syncs only.
+//    "path": [0, 2],               // Syncs returned by Diff only.
+//    "index": 0,                   // Child syncs only, always present.
+//    "from": 0,                    // Move syncs only, always present.
+//    "node": <node>,               // Full, insert and replace syncs only.
+//    "id": "<node id>",            // Attr, remove, move and text syncs only.
+//    "text": "Hello",              // Text syncs only.
+//    "attributes": {"name": "value", ...}, // Attr syncs only.
+//    "removed": ["name", ...],             // Attr syncs only.
+//    "properties": {"name": "value", ...}  // Attr syncs only.
+//  }
+//
+// Only the syncs which create nodes, full, insert and replace syncs, carry the
+// subtree of their node. The other syncs designate their node by its id, which
+// the driver already knows, and text syncs carry the new text. Their decoded
+// node only has the id, and the text for text syncs.
+// Syncs returned by Diff designate their nodes by path as well, since the
+// nodes of a tree which is not mounted have no id.
+//
+// A node is encoded as:
+//  {
+//    "id": "<id>",
+//    "type": "element" | "text" | "component",
+//    "tag": "div",                 // Element and component nodes only.
+//    "text": "Hello",              // Text nodes only.
+//    "attributes": {"name": "value", ...},
+//    "properties": {"name": "value", ...}, // Element nodes only.
+//    "children": [<node>, ...]
+//  }
+//
+// Mounted component nodes are encoded as the root element of their component,
+// like in the output of Markup. Component nodes are only encoded as is when
+// they are not mounted.
+// Attributes are encoded with the value they have in the markup: drivers wire
+// event attributes and complete href attributes as Markup does. Event
+// attributes contain the handler followed by its modifiers, like
+// "Search|debounce:300ms|key:Enter". Properties are
+// set on the native element rather than as attributes.
+//
+// Binary encoding
+//
+// The compact binary encoding uses unsigned varints (uvarint) as defined by
+// the encoding/binary standard package. Strings are encoded as their length
+// in bytes followed by their UTF-8 bytes. Ids are encoded as their 16 bytes.
+// Attributes and properties are encoded as their count followed by name and
+// value strings.
+// Removed attributes are encoded as their count followed by name strings.
+// Indexes are encoded as uvarints.
+//  batch:  version byte, syncs count, syncs
+//  sync:   scope byte, parent id, has path byte (0 or 1), path, index, from, node or node ref, attributes, removed attributes, properties
+//  path:   indexes count, indexes
+//  node ref: id, text (text syncs only)
+//  node:   type byte, id, tag, text, attributes, properties, children count, children
+// Scope bytes follow the SyncScope values: full = 0, attr = 1, insert = 2,
+// remove = 3, move = 4, replace = 5 and text = 6. Type bytes follow the
+// NodeType values: element = 0, component = 1 and text = 2. Full, insert and
+// replace syncs encode a node, the other syncs a node ref.
+const WireVersion = 4
+
+var (
+	wireScopes = map[SyncScope]string{
+		FullSync:     "full",
+		AttrSync:     "attr",
+		InsertChild:  "insert",
+		RemoveChild:  "remove",
+		MoveChild:    "move",
+		ReplaceChild: "replace",
+		TextSync:     "text",
+	}
+
+	wireNodeTypes = map[NodeType]string{
+		HTMLNode:      "element",
+		ComponentNode: "component",
+		TextNode:      "text",
+	}
+)
+
+type wireBatch struct {
+	Version int    `json:"version"`
+	Syncs   []Sync `json:"syncs"`
+}
+
+type wireSync struct {
+	Scope      string       `json:"scope"`
+	Parent     string       `json:"parent,omitempty"`
+	Path       *[]int       `json:"path,omitempty"`
+	Index      *int         `json:"index,omitempty"`
+	From       *int         `json:"from,omitempty"`
+	Node       *Node        `json:"node,omitempty"`
+	ID         string       `json:"id,omitempty"`
+	Text       string       `json:"text,omitempty"`
+	Attributes AttributeMap `json:"attributes,omitempty"`
+	Removed    []string     `json:"removed,omitempty"`
+	Properties AttributeMap `json:"properties,omitempty"`
+}
+
+type wireNode struct {
+	ID         string       `json:"id,omitempty"`
+	Type       string       `json:"type"`
+	Tag        string       `json:"tag,omitempty"`
+	Text       string       `json:"text,omitempty"`
+	Attributes AttributeMap `json:"attributes,omitempty"`
+	Properties AttributeMap `json:"properties,omitempty"`
+	Children   []*Node      `json:"children,omitempty"`
+}
+
+// MarshalSyncs returns the versioned JSON encoding of syncs.
+func MarshalSyncs(syncs []Sync) ([]byte, error) {
+	return json.Marshal(wireBatch{
+		Version: WireVersion,
+		Syncs:   syncs,
+	})
+}
+
+// UnmarshalSyncs parses syncs from their versioned JSON encoding.
+// Nodes of the returned syncs are not mounted.
+func UnmarshalSyncs(data []byte) (syncs []Sync, err error) {
+	var batch wireBatch
+	if err = json.Unmarshal(data, &batch); err != nil {
+		return
+	}
+
+	if batch.Version != WireVersion {
+		err = errors.Errorf("unsupported wire version: %v", batch.Version)
+		return
+	}
+
+	syncs = batch.Syncs
+	return
+}
+
+// MarshalJSON satisfies the json.Marshaler interface.
+func (s Sync) MarshalJSON() ([]byte, error) {
+	scope, ok := wireScopes[s.Scope]
+	if !ok {
+		return nil, errors.Errorf("unknown sync scope: %v", s.Scope)
+	}
+
+	ws := wireSync{
+		Scope:      scope,
+		Attributes: s.Attributes,
+		Removed:    s.RemovedAttributes,
+		Properties: s.Properties,
+	}
+
+	if s.ParentID != uuid.Nil {
+		ws.Parent = s.ParentID.String()
+	}
+
+	if s.Path != nil {
+		ws.Path = &s.Path
+	}
+
+	// Indexes are encoded even when they are 0.
+	if isChildSync(s.Scope) {
+		ws.Index = &s.Index
+	}
+
+	if s.Scope == MoveChild {
+		ws.From = &s.From
+	}
+
+	if sendsSubtree(s.Scope) {
+		ws.Node = s.Node
+	} else if n := wireNodeRef(s.Node); n != nil {
+		if n.ID != uuid.Nil {
+			ws.ID = n.ID.String()
+		}
+
+		if s.Scope == TextSync {
+			ws.Text = n.Text
+		}
+	}
+	return json.Marshal(ws)
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface.
+func (s *Sync) UnmarshalJSON(data []byte) (err error) {
+	var ws wireSync
+	if err = json.Unmarshal(data, &ws); err != nil {
+		return
+	}
+
+	scope, ok := syncScope(ws.Scope)
+	if !ok {
+		return errors.Errorf("unknown sync scope: %v", ws.Scope)
+	}
+
+	*s = Sync{
+		Scope:             scope,
+		Node:              ws.Node,
+		Attributes:        ws.Attributes,
+		RemovedAttributes: ws.Removed,
+		Properties:        ws.Properties,
+	}
+
+	if ws.Path != nil {
+		s.Path = *ws.Path
+	}
+
+	if ws.Index != nil {
+		s.Index = *ws.Index
+	}
+
+	if ws.From != nil {
+		s.From = *ws.From
+	}
+
+	if !sendsSubtree(scope) {
+		s.Node = &Node{Text: ws.Text}
+
+		if scope == TextSync {
+			s.Node.Type = TextNode
+		}
+
+		if len(ws.ID) != 0 {
+			if s.Node.ID, err = uuid.FromString(ws.ID); err != nil {
+				return
+			}
+		}
+	}
+
+	if len(ws.Parent) != 0 {
+		s.ParentID, err = uuid.FromString(ws.Parent)
+	}
+	return
+}
+
+// sendsSubtree reports whether the syncs of scope carry the subtree of their
+// node. The other syncs only designate their node.
+func sendsSubtree(scope SyncScope) bool {
+	return scope == FullSync || scope == InsertChild || scope == ReplaceChild
+}
+
+// isChildSync reports whether the syncs of scope designate a child of the node
+// designated by ParentID, at Index.
+func isChildSync(scope SyncScope) bool {
+	return scope != FullSync && scope != AttrSync
+}
+
+// wireNodeRef returns the node which represents n on the native side: the
+// root of its component when n is a mounted component node.
+func wireNodeRef(n *Node) *Node {
+	for n != nil && n.Type == ComponentNode && n.compo != nil {
+		n = n.compo.Root
+	}
+	return n
+}
+
+func syncScope(name string) (scope SyncScope, ok bool) {
+	for scope, n := range wireScopes {
+		if n == name {
+			return scope, true
+		}
+	}
+	return
+}
+
+// MarshalJSON satisfies the json.Marshaler interface.
+// Mounted component nodes are encoded as the root of their component.
+func (n *Node) MarshalJSON() ([]byte, error) {
+	if n.Type == ComponentNode && n.compo != nil {
+		return n.compo.Root.MarshalJSON()
+	}
+
+	wn := wireNode{
+		Type:       wireNodeTypes[n.Type],
+		Tag:        n.Tag,
+		Text:       n.Text,
+		Attributes: n.Attributes,
+		Properties: n.Properties,
+		Children:   n.Children,
+	}
+
+	if n.ID != uuid.Nil {
+		wn.ID = n.ID.String()
+	}
+	return json.Marshal(wn)
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface.
+func (n *Node) UnmarshalJSON(data []byte) (err error) {
+	var wn wireNode
+	if err = json.Unmarshal(data, &wn); err != nil {
+		return
+	}
+
+	nodeType, ok := nodeTypeFromWire(wn.Type)
+	if !ok {
+		return errors.Errorf("unknown node type: %v", wn.Type)
+	}
+
+	*n = Node{
+		Type:       nodeType,
+		Tag:        wn.Tag,
+		Text:       wn.Text,
+		Attributes: wn.Attributes,
+		Properties: wn.Properties,
+		Children:   wn.Children,
+	}
+
+	for _, c := range n.Children {
+		c.Parent = n
+	}
+
+	if len(wn.ID) != 0 {
+		n.ID, err = uuid.FromString(wn.ID)
+	}
+	return
+}
+
+func nodeTypeFromWire(name string) (nodeType NodeType, ok bool) {
+	for nodeType, n := range wireNodeTypes {
+		if n == name {
+			return nodeType, true
+		}
+	}
+	return
+}
+
+// MarshalSyncsBinary returns the versioned compact binary encoding of syncs.
+func MarshalSyncsBinary(syncs []Sync) ([]byte, error) {
+	w := &wireWriter{}
+	w.WriteByte(WireVersion)
+	w.uvarint(uint64(len(syncs)))
+
+	for _, s := range syncs {
+		if _, ok := wireScopes[s.Scope]; !ok {
+			return nil, errors.Errorf("unknown sync scope: %v", s.Scope)
+		}
+
+		w.WriteByte(byte(s.Scope))
+		w.Write(s.ParentID.Bytes())
+		w.path(s.Path)
+		w.uvarint(uint64(s.Index))
+		w.uvarint(uint64(s.From))
+
+		if sendsSubtree(s.Scope) {
+			w.node(s.Node)
+		} else {
+			w.nodeRef(s.Node, s.Scope == TextSync)
+		}
+
+		w.attributes(s.Attributes)
+		w.uvarint(uint64(len(s.RemovedAttributes)))
+
+		for _, name := range s.RemovedAttributes {
+			w.string(name)
+		}
+
+		w.attributes(s.Properties)
+	}
+	return w.Bytes(), nil
+}
+
+// UnmarshalSyncsBinary parses syncs from their versioned compact binary
+// encoding. Nodes of the returned syncs are not mounted.
+func UnmarshalSyncsBinary(data []byte) (syncs []Sync, err error) {
+	r := &wireReader{Reader: bytes.NewReader(data)}
+
+	if version := r.byte(); r.err == nil && version != WireVersion {
+		err = errors.Errorf("unsupported wire version: %v", version)
+		return
+	}
+
+	count := r.uvarint()
+
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		s := Sync{
+			Scope:    SyncScope(r.byte()),
+			ParentID: r.id(),
+			Path:     r.path(),
+			Index:    int(r.uvarint()),
+			From:     int(r.uvarint()),
+		}
+
+		if _, ok := wireScopes[s.Scope]; !ok && r.err == nil {
+			err = errors.Errorf("unknown sync scope: %v", s.Scope)
+			return
+		}
+
+		if sendsSubtree(s.Scope) {
+			s.Node = r.node()
+		} else {
+			s.Node = r.nodeRef(s.Scope == TextSync)
+		}
+
+		s.Attributes = r.attributes()
+		removed := r.uvarint()
+
+		for j := uint64(0); j < removed && r.err == nil; j++ {
+			s.RemovedAttributes = append(s.RemovedAttributes, r.string())
+		}
+
+		s.Properties = r.attributes()
+		syncs = append(syncs, s)
+	}
+
+	if err = r.err; err != nil {
+		syncs = nil
+		err = errors.Wrap(err, "malformed binary syncs")
+	}
+	return
+}
+
+type wireWriter struct {
+	bytes.Buffer
+}
+
+func (w *wireWriter) uvarint(v uint64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(b, v)
+	w.Write(b[:n])
+}
+
+func (w *wireWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.WriteString(s)
+}
+
+func (w *wireWriter) attributes(attrs AttributeMap) {
+	w.uvarint(uint64(len(attrs)))
+
+	for name, value := range attrs {
+		w.string(name)
+		w.string(value)
+	}
+}
+
+func (w *wireWriter) node(n *Node) {
+	if n.Type == ComponentNode && n.compo != nil {
+		w.node(n.compo.Root)
+		return
+	}
+
+	w.WriteByte(byte(n.Type))
+	w.Write(n.ID.Bytes())
+	w.string(n.Tag)
+	w.string(n.Text)
+	w.attributes(n.Attributes)
+	w.attributes(n.Properties)
+	w.uvarint(uint64(len(n.Children)))
+
+	for _, c := range n.Children {
+		w.node(c)
+	}
+}
+
+func (w *wireWriter) path(path []int) {
+	if path == nil {
+		w.WriteByte(0)
+		return
+	}
+
+	w.WriteByte(1)
+	w.uvarint(uint64(len(path)))
+
+	for _, i := range path {
+		w.uvarint(uint64(i))
+	}
+}
+
+func (w *wireWriter) nodeRef(n *Node, withText bool) {
+	var id uuid.UUID
+	var text string
+
+	if n = wireNodeRef(n); n != nil {
+		id = n.ID
+		text = n.Text
+	}
+
+	w.Write(id.Bytes())
+
+	if withText {
+		w.string(text)
+	}
+}
+
+// wireReader reads binary encoded values. Reading stops at the first error,
+// which is kept in err.
+type wireReader struct {
+	*bytes.Reader
+	err error
+}
+
+func (r *wireReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+
+	b, err := r.ReadByte()
+	r.err = err
+	return b
+}
+
+func (r *wireReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+
+	v, err := binary.ReadUvarint(r)
+	r.err = err
+	return v
+}
+
+func (r *wireReader) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if n > uint64(r.Len()) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+
+	b := make([]byte, n)
+	_, r.err = io.ReadFull(r, b)
+	return b
+}
+
+func (r *wireReader) string() string {
+	return string(r.bytes(r.uvarint()))
+}
+
+func (r *wireReader) id() uuid.UUID {
+	b := r.bytes(uuid.Size)
+	if r.err != nil {
+		return uuid.Nil
+	}
+
+	id, err := uuid.FromBytes(b)
+	r.err = err
+	return id
+}
+
+func (r *wireReader) attributes() AttributeMap {
+	count := r.uvarint()
+	if count == 0 {
+		return nil
+	}
+
+	attrs := AttributeMap{}
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		name := r.string()
+		attrs[name] = r.string()
+	}
+	return attrs
+}
+
+func (r *wireReader) node() *Node {
+	n := &Node{
+		Type: NodeType(r.byte()),
+		ID:   r.id(),
+		Tag:  r.string(),
+		Text: r.string(),
+	}
+
+	if _, ok := wireNodeTypes[n.Type]; !ok && r.err == nil {
+		r.err = errors.Errorf("unknown node type: %v", n.Type)
+	}
+
+	n.Attributes = r.attributes()
+	n.Properties = r.attributes()
+	count := r.uvarint()
+
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		c := r.node()
+		c.Parent = n
+		n.Children = append(n.Children, c)
+	}
+	return n
+}
+
+func (r *wireReader) nodeRef(withText bool) *Node {
+	n := &Node{
+		ID: r.id(),
+	}
+
+	if withText {
+		n.Type = TextNode
+		n.Text = r.string()
+	}
+	return n
+}
+
+func (r *wireReader) path() []int {
+	if r.byte() != 1 {
+		return nil
+	}
+
+	count := r.uvarint()
+	if count > uint64(r.Len()) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+
+	path := make([]int, 0, count)
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		path = append(path, int(r.uvarint()))
+	}
+	return path
+}
@@ -0,0 +1,325 @@
+package markup
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/satori/go.uuid"
+)
+
+func wireTestSyncs(t *testing.T) (syncs []Sync, dismount func()) {
+	c := &CompoSync{}
+	if _, err := Mount(c, uuid.NewV1()); err != nil {
+		t.Fatal(err)
+	}
+
+	c.TextChange = true
+	c.HTMLAttrChange = true
+	c.TypeChange = true
+	c.AddRemove = true
+
+	syncs, err := Synchronize(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	syncs = append(syncs, Sync{
+		Scope:             AttrSync,
+		Node:              Root(c),
+		Attributes:        AttributeMap{"class": ""},
+		RemovedAttributes: []string{"hidden", "title"},
+		Properties:        AttributeMap{"value": "Maxoo"},
+	})
+	return syncs, func() { Dismount(c) }
+}
+
+func compareWireNodes(t *testing.T, expected *Node, n *Node) {
+	if expected.Type == ComponentNode && expected.compo != nil {
+		expected = expected.compo.Root
+	}
+
+	if n.ID != expected.ID || n.Type != expected.Type || n.Tag != expected.Tag || n.Text != expected.Text {
+		t.Fatalf("n should be %v: %v", expected, n)
+	}
+
+	if len(n.Attributes) != len(expected.Attributes) {
+		t.Fatalf("n attributes should be %v: %v", expected.Attributes, n.Attributes)
+	}
+
+	for name, value := range expected.Attributes {
+		if n.Attributes[name] != value {
+			t.Errorf("n attribute %v should be %v: %v", name, value, n.Attributes[name])
+		}
+	}
+
+	if len(n.Children) != len(expected.Children) {
+		t.Fatalf("n children len should be %v: %v", len(expected.Children), len(n.Children))
+	}
+
+	for i, c := range n.Children {
+		if c.Parent != n {
+			t.Error("child parent should be n")
+		}
+		compareWireNodes(t, expected.Children[i], c)
+	}
+}
+
+func compareWireSyncs(t *testing.T, expected []Sync, syncs []Sync) {
+	if len(syncs) != len(expected) {
+		t.Fatalf("syncs len should be %v: %v", len(expected), len(syncs))
+	}
+
+	for i, s := range syncs {
+		e := expected[i]
+
+		if s.Scope != e.Scope || s.ParentID != e.ParentID || s.Index != e.Index || s.From != e.From {
+			t.Errorf("sync %v should be %+v: %+v", i, e, s)
+		}
+
+		if !reflect.DeepEqual(s.Path, e.Path) {
+			t.Errorf("sync %v path should be %v: %v", i, e.Path, s.Path)
+		}
+
+		if len(s.Attributes) != len(e.Attributes) {
+			t.Errorf("sync %v attributes should be %v: %v", i, e.Attributes, s.Attributes)
+		}
+
+		if len(s.RemovedAttributes) != len(e.RemovedAttributes) {
+			t.Errorf("sync %v removed attributes should be %v: %v", i, e.RemovedAttributes, s.RemovedAttributes)
+		}
+
+		if len(s.Properties) != len(e.Properties) || s.Properties["value"] != e.Properties["value"] {
+			t.Errorf("sync %v properties should be %v: %v", i, e.Properties, s.Properties)
+		}
+
+		for j, name := range e.RemovedAttributes {
+			if j < len(s.RemovedAttributes) && s.RemovedAttributes[j] != name {
+				t.Errorf("sync %v removed attribute %v should be %v: %v", i, j, name, s.RemovedAttributes[j])
+			}
+		}
+
+		if sendsSubtree(e.Scope) {
+			compareWireNodes(t, e.Node, s.Node)
+			continue
+		}
+
+		ref := wireNodeRef(e.Node)
+
+		if s.Node.ID != ref.ID || len(s.Node.Children) != 0 || len(s.Node.Attributes) != 0 {
+			t.Errorf("sync %v node should only have the id %v: %v", i, ref.ID, s.Node)
+		}
+
+		if e.Scope == TextSync && s.Node.Text != ref.Text {
+			t.Errorf("sync %v text should be %v: %v", i, ref.Text, s.Node.Text)
+		}
+	}
+}
+
+func TestMarshalSyncs(t *testing.T) {
+	syncs, dismount := wireTestSyncs(t)
+	defer dismount()
+
+	data, err := MarshalSyncs(syncs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(string(data))
+
+	decoded, err := UnmarshalSyncs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compareWireSyncs(t, syncs, decoded)
+}
+
+func TestMarshalSyncsBinary(t *testing.T) {
+	syncs, dismount := wireTestSyncs(t)
+	defer dismount()
+
+	data, err := MarshalSyncsBinary(syncs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := UnmarshalSyncsBinary(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compareWireSyncs(t, syncs, decoded)
+
+	// Truncated.
+	if _, err = UnmarshalSyncsBinary(data[:len(data)/2]); err == nil {
+		t.Error("err should not be nil")
+	}
+	t.Log(err)
+}
+
+func TestMarshalSyncsErrors(t *testing.T) {
+	syncs := []Sync{{Scope: 42}}
+
+	if _, err := MarshalSyncs(syncs); err == nil {
+		t.Error("err should not be nil")
+	}
+
+	if _, err := MarshalSyncsBinary(syncs); err == nil {
+		t.Error("err should not be nil")
+	}
+}
+
+func TestUnmarshalSyncsErrors(t *testing.T) {
+	tests := []string{
+		`{"version": 42, "syncs": []}`,
+		`{"version": 4, "syncs": [{"scope": "unknown"}]}`,
+		`{"version": 4, "syncs": [{"scope": "full", "node": {"type": "unknown"}}]}`,
+		`{"version": 4, "syncs": [{"scope": "insert", "parent": "42"}]}`,
+		`{"version": 4, "syncs": [{"scope": "full", "node": {"type": "text", "id": "42"}}]}`,
+		`{"version": 1`,
+	}
+
+	for _, test := range tests {
+		if _, err := UnmarshalSyncs([]byte(test)); err == nil {
+			t.Errorf("err should not be nil: %v", test)
+		}
+	}
+
+	if _, err := UnmarshalSyncsBinary([]byte{42}); err == nil {
+		t.Error("err should not be nil")
+	}
+
+	if _, err := UnmarshalSyncsBinary([]byte{WireVersion, 1, 42}); err == nil {
+		t.Error("err should not be nil")
+	}
+}
+
+func TestNodeMarshalJSON(t *testing.T) {
+	n := &Node{
+		Type: ComponentNode,
+		Tag:  "Hello",
+		Attributes: AttributeMap{
+			"Name": "Maxence",
+		},
+	}
+
+	data, err := json.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Node
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	compareWireNodes(t, n, &decoded)
+}
+
+func TestMarshalSyncsIndexZero(t *testing.T) {
+	c := &CompoSync{}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	syncs := []Sync{
+		{Scope: InsertChild, ParentID: root.ID, Node: root.Children[0]},
+		{Scope: RemoveChild, ParentID: root.ID, Node: root.Children[0]},
+		{Scope: AttrSync, Node: root, Attributes: AttributeMap{"class": "boo"}},
+		{Scope: MoveChild, ParentID: root.ID, Index: 1, Node: root.Children[0]},
+	}
+
+	data, err := MarshalSyncs(syncs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var batch struct {
+		Syncs []map[string]interface{}
+	}
+	if err = json.Unmarshal(data, &batch); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, s := range batch.Syncs[:2] {
+		if index, ok := s["index"]; !ok || index != float64(0) {
+			t.Errorf("sync %v index should be 0: %v", i, s)
+		}
+	}
+
+	if _, ok := batch.Syncs[2]["index"]; ok {
+		t.Error("attr sync should not have an index:", batch.Syncs[2])
+	}
+
+	if from, ok := batch.Syncs[3]["from"]; !ok || from != float64(0) {
+		t.Error("move sync from should be 0:", batch.Syncs[3])
+	}
+
+	for i, s := range batch.Syncs[:3] {
+		if _, ok := s["from"]; ok {
+			t.Errorf("sync %v should not have a from: %v", i, s)
+		}
+	}
+
+	decoded, err := UnmarshalSyncs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareWireSyncs(t, syncs, decoded)
+}
+
+func TestMarshalSyncsNodeRefs(t *testing.T) {
+	c := &CompoSync{}
+
+	root, err := Mount(c, uuid.NewV1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Dismount(c)
+
+	syncs := []Sync{
+		{Scope: AttrSync, Node: root, Attributes: AttributeMap{"class": "boo"}},
+		{Scope: MoveChild, ParentID: root.ID, Index: 1, Node: root.Children[0]},
+		{Scope: RemoveChild, ParentID: root.ID, Node: root.Children[1]},
+	}
+
+	data, err := MarshalSyncs(syncs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var batch struct {
+		Syncs []map[string]interface{}
+	}
+	if err = json.Unmarshal(data, &batch); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, s := range batch.Syncs {
+		if _, hasNode := s["node"]; hasNode {
+			t.Errorf("sync %v should not carry a node: %v", i, s)
+		}
+
+		if id := s["id"]; id != wireNodeRef(syncs[i].Node).ID.String() {
+			t.Errorf("sync %v id should be %v: %v", i, syncs[i].Node.ID, id)
+		}
+	}
+
+	decoded, err := UnmarshalSyncs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareWireSyncs(t, syncs, decoded)
+
+	if data, err = MarshalSyncsBinary(syncs); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded, err = UnmarshalSyncsBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	compareWireSyncs(t, syncs, decoded)
+}